@@ -0,0 +1,150 @@
+package microClustering
+
+import (
+	"fmt"
+	"sort"
+)
+
+/*
+  Apprentissage actif
+
+  - l'incertitude d'un point est estimée par :
+      - l'écart entre les probabilités des deux classes les plus probables (vote kNN)
+      - l'appartenance à des µC de plusieurs classes (zones de recouvrement)
+      - le fait d'être un outlier pour toutes les classes (nouveau comportement)
+  - Query sélectionne dans un pool les points les plus incertains
+  - ActiveLearner filtre un flux avec un budget de questions et apprend les réponses par PartialFit
+*/
+
+// QueryStrategy définit la mesure d'incertitude utilisée pour choisir les points à étiqueter
+type QueryStrategy int
+
+const (
+	// MarginQuery : 1 - écart entre les probabilités des deux classes les plus probables
+	MarginQuery QueryStrategy = iota
+	// SharedMCQuery : part des classes dont un µC contient le point (au-delà de la première)
+	SharedMCQuery
+	// OutlierQuery : 1 si le point est un outlier pour toutes les classes, 0 sinon
+	OutlierQuery
+)
+
+// Uncertainty renvoie l'incertitude du classifieur sur le point 'x', entre 0 (certain) et 1 (incertain)
+func (c *Classifier) Uncertainty(x []float64, k int, strategy QueryStrategy) float64 {
+	if len(c.classes) == 0 {
+		return 1
+	}
+	switch strategy {
+	case SharedMCQuery:
+		if len(c.classes) < 2 {
+			return 0
+		}
+		nb := 0
+		for _, cl := range c.classes {
+			cl.mu.RLock()
+			for _, mc := range cl.mc {
+				if cl.distance(x, mc.Center) <= cl.radiusOf(mc) {
+					nb++
+					break
+				}
+			}
+			cl.mu.RUnlock()
+		}
+		if nb < 2 {
+			return 0
+		}
+		return float64(nb-1) / float64(len(c.classes)-1)
+	case OutlierQuery:
+		if c.IsOutlier(x) {
+			return 1
+		}
+		return 0
+	default:
+		return 1 - margin(c.PredictProba(x, k))
+	}
+}
+
+// Query renvoie les indices des 'budget' points de 'pool' les plus incertains, du plus incertain au moins incertain.
+// Un budget négatif est ramené à 0.
+func (c *Classifier) Query(pool [][]float64, budget int, k int, strategy QueryStrategy) []int {
+	if budget < 0 {
+		budget = 0
+	}
+	scores := make([]float64, len(pool))
+	ids := make([]int, len(pool))
+	for i, x := range pool {
+		scores[i] = c.Uncertainty(x, k, strategy)
+		ids[i] = i
+	}
+	sort.SliceStable(ids, func(i, j int) bool { return scores[ids[i]] > scores[ids[j]] })
+	if budget < len(ids) {
+		ids = ids[:budget]
+	}
+	return ids
+}
+
+// ActiveLearner choisit dans un flux les points à faire étiqueter, dans la limite d'un budget
+type ActiveLearner struct {
+	Classifier *Classifier
+	Strategy   QueryStrategy
+	K          int     // nombre de voisins utilisé pour le vote
+	Threshold  float64 // incertitude minimum pour demander un label
+	Budget     int     // nombre maximum de labels demandés
+	queried    int     // nombre de labels demandés
+}
+
+// NewActiveLearner crée un ActiveLearner pour le classifieur 'c'
+func NewActiveLearner(c *Classifier, strategy QueryStrategy, k int, threshold float64, budget int) *ActiveLearner {
+	return &ActiveLearner{Classifier: c, Strategy: strategy, K: k, Threshold: threshold, Budget: budget}
+}
+
+// Offer présente un point du flux et renvoie true si son label doit être demandé ; le budget est alors décompté
+func (a *ActiveLearner) Offer(x []float64) bool {
+	if a.queried >= a.Budget {
+		return false
+	}
+	if a.Classifier.Uncertainty(x, a.K, a.Strategy) < a.Threshold {
+		return false
+	}
+	a.queried++
+	return true
+}
+
+// QueryPool sélectionne les points les plus incertains du pool dans la limite du budget restant et les décompte
+func (a *ActiveLearner) QueryPool(pool [][]float64) []int {
+	ids := a.Classifier.Query(pool, a.Remaining(), a.K, a.Strategy)
+	selected := []int{}
+	for _, id := range ids {
+		if a.Classifier.Uncertainty(pool[id], a.K, a.Strategy) < a.Threshold {
+			break
+		}
+		selected = append(selected, id)
+	}
+	a.queried += len(selected)
+	return selected
+}
+
+// Answer apprend le label 'y' fourni pour le point 'x' (voir Classifier.PartialFit)
+func (a *ActiveLearner) Answer(x []float64, y int) error {
+	return a.Classifier.PartialFit(x, y)
+}
+
+// AnswerXY apprend les labels 'Y' fournis pour les points 'X'
+func (a *ActiveLearner) AnswerXY(X [][]float64, Y []int) error {
+	if len(X) != len(Y) {
+		return fmt.Errorf("data and label mismatch")
+	}
+	for i := range X {
+		if err := a.Answer(X[i], Y[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Remaining renvoie le nombre de labels pouvant encore être demandés
+func (a *ActiveLearner) Remaining() int {
+	if a.queried >= a.Budget {
+		return 0
+	}
+	return a.Budget - a.queried
+}
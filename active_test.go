@@ -0,0 +1,54 @@
+package microClustering
+
+import (
+	"testing"
+)
+
+func TestActiveLearning(t *testing.T) {
+
+	SetDistanceFunction("euclidian")
+	c := NewClassifier(0, 2, 1, 1, 3.0)
+	c.Weighting = InverseDistanceWeighting
+	if err := c.FitXY([][]float64{{0, 0}, {0.2, 0}, {3, 0}, {3.2, 0}}, []int{1, 1, 2, 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	pool := [][]float64{{0, 0}, {1.6, 0}, {3.2, 0}, {50, 50}}
+
+	// le point à mi-chemin entre les classes est le plus incertain pour le vote
+	if ids := c.Query(pool, 1, 2, MarginQuery); ids[0] != 1 {
+		t.Errorf("margin query : %v", ids)
+	}
+	if ids := c.Query(pool, -1, 2, MarginQuery); len(ids) != 0 {
+		t.Errorf("negative budget : %v", ids)
+	}
+	// seul le point lointain est un outlier pour toutes les classes
+	if ids := c.Query(pool, 1, 2, OutlierQuery); ids[0] != 3 {
+		t.Errorf("outlier query : %v", ids)
+	}
+	// le point à mi-chemin appartient à un µC de chaque classe
+	if u := c.Uncertainty([]float64{1.6, 0}, 2, SharedMCQuery); u != 1 {
+		t.Errorf("shared µC uncertainty : %f", u)
+	}
+	if u := c.Uncertainty([]float64{0, 0}, 2, SharedMCQuery); u != 0 {
+		t.Errorf("shared µC uncertainty : %f", u)
+	}
+
+	a := NewActiveLearner(c, OutlierQuery, 2, 0.5, 2)
+	if a.Offer([]float64{0, 0}) {
+		t.Error("certain point queried")
+	}
+	if !a.Offer([]float64{50, 50}) {
+		t.Error("outlier not queried")
+	}
+	a.Answer([]float64{50, 50}, 3)
+	if a.Offer([]float64{50, 50}) {
+		t.Error("point queried after its label was learned")
+	}
+	if ids := a.QueryPool([][]float64{{-40, 0}, {0, 40}, {0, 0}}); len(ids) != 1 || a.Remaining() != 0 {
+		t.Errorf("budget not respected : %v, remaining=%d", ids, a.Remaining())
+	}
+	if a.Offer([]float64{90, 90}) {
+		t.Error("budget exceeded")
+	}
+}
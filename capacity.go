@@ -0,0 +1,154 @@
+package microClustering
+
+import (
+	"math"
+	"math/rand"
+)
+
+// CapacityPolicy définit comment libérer un µC lorsque le nombre maximum de µC est dépassé
+type CapacityPolicy int
+
+const (
+	// MergeClosest fusionne les deux µC les plus proches (fusion exacte si les cluster features sont maintenues)
+	MergeClosest CapacityPolicy = iota
+	// EvictOldest supprime le µC mis à jour le moins récemment
+	EvictOldest
+	// EvictLightest supprime le µC de plus faible poids
+	EvictLightest
+	// MergeNewest fusionne le dernier µC créé avec son plus proche voisin : un seul parcours des µC au lieu de toutes
+	// les paires, mais ce voisin, parfois éloigné, est déplacé vers la nouvelle région
+	MergeNewest
+)
+
+// CapacityEvent décrit un µC fusionné ou supprimé pour respecter MaxMicroClusters
+type CapacityEvent struct {
+	Merged bool      // true si le µC a été fusionné, false s'il a été supprimé
+	Center []float64 // centre du µC
+	Zones  []int     // nombre de mesures par zone
+	Weight int       // poids du µC
+	Into   []float64 // centre du µC résultant de la fusion
+}
+
+// enforceCapacity fusionne ou supprime un µC pour revenir à MaxMicroClusters.
+// Le dernier µC créé n'est jamais supprimé, sinon aucune nouvelle région ne pourrait apparaître.
+func (c *Clusterer) enforceCapacity() {
+	for len(c.mc) > c.MaxMicroClusters && len(c.mc) > 1 {
+		last := len(c.mc) - 1
+
+		switch c.CapacityPolicy {
+		case EvictOldest, EvictLightest:
+			victim := 0
+			for i := 1; i < last; i++ {
+				if c.CapacityPolicy == EvictOldest && c.mc[i].LastUpdate < c.mc[victim].LastUpdate ||
+					c.CapacityPolicy == EvictLightest && c.mc[i].Weight < c.mc[victim].Weight {
+					victim = i
+				}
+			}
+			removed := c.mc[victim]
+			c.removeMC(victim)
+			c.notifyCapacity(CapacityEvent{Center: removed.Center, Zones: removed.Zones, Weight: removed.Weight})
+
+		case MergeNewest:
+			// fusionne le dernier µC créé avec son plus proche voisin
+			nearest := 0
+			min := math.MaxFloat64
+			for i := 0; i < last; i++ {
+				if d := c.distance(c.mc[i].Center, c.mc[last].Center); d < min {
+					min = d
+					nearest = i
+				}
+			}
+			c.mergeMC(nearest, last)
+
+		default:
+			// recherche les deux µC les plus proches
+			a, b := 0, 1
+			min := math.MaxFloat64
+			for i := range c.mc {
+				for j := i + 1; j < len(c.mc); j++ {
+					if d := c.distance(c.mc[i].Center, c.mc[j].Center); d < min {
+						min = d
+						a, b = i, j
+					}
+				}
+			}
+			c.mergeMC(a, b)
+		}
+	}
+}
+
+// mergeMC fusionne le µC 'b' dans le µC 'a' (a < b) puis supprime 'b'
+func (c *Clusterer) mergeMC(a, b int) {
+	merged := *c.mc[b]
+	c.mc[a].merge(c.mc[b], c.ReservoirSize)
+	c.updateRadius(c.mc[a])
+	c.removeMC(b)
+	c.notifyCapacity(CapacityEvent{Merged: true, Center: merged.Center, Zones: merged.Zones, Weight: merged.Weight, Into: c.mc[a].Center})
+}
+
+func (c *Clusterer) notifyCapacity(event CapacityEvent) {
+	if c.OnCapacity != nil {
+		c.OnCapacity(event)
+	}
+}
+
+// removeMC supprime le µC 'id' en conservant l'ordre des µC
+func (c *Clusterer) removeMC(id int) {
+	copy(c.mc[id:], c.mc[id+1:])
+	c.mc[len(c.mc)-1] = nil
+	c.mc = c.mc[:len(c.mc)-1]
+}
+
+// merge fusionne le µC 'other' dans le µC.
+// Le nouveau centre est la moyenne pondérée des centres, exacte lorsque les cluster features sont maintenues.
+// Les zones sont additionnées zone par zone, ce qui est une approximation si les rayons des µC diffèrent.
+func (mc *microcluster) merge(other *microcluster, reservoirSize int) {
+	weight := mc.Weight + other.Weight
+	center := make([]float64, len(mc.Center))
+	if mc.LS != nil && other.LS != nil {
+		for i := range mc.LS {
+			mc.LS[i] += other.LS[i]
+			mc.SS[i] += other.SS[i]
+			center[i] = mc.LS[i] / float64(weight)
+		}
+	} else {
+		for i := range mc.Center {
+			center[i] = (float64(mc.Weight)*mc.Center[i] + float64(other.Weight)*other.Center[i]) / float64(weight)
+		}
+		mc.LS, mc.SS = nil, nil // les cluster features ne sont plus exactes
+	}
+	mc.Center = center
+	mc.Weight = weight
+
+	for z := range mc.Zones {
+		if z < len(other.Zones) {
+			mc.Zones[z] += other.Zones[z]
+		}
+	}
+
+	if other.Target != nil {
+		if mc.Target == nil {
+			mc.Target = &targetStats{}
+		}
+		mc.Target.merge(*other.Target)
+	}
+
+	for label, n := range other.Labels {
+		if mc.Labels == nil {
+			mc.Labels = make(map[int]float64)
+		}
+		mc.Labels[label] += n
+	}
+
+	if other.LastUpdate > mc.LastUpdate {
+		mc.LastUpdate = other.LastUpdate
+	}
+
+	// fusion des reservoirs : conserve un sous-ensemble aléatoire des exemples
+	mc.Exemplars = append(mc.Exemplars, other.Exemplars...)
+	mc.Seen += other.Seen
+	if reservoirSize > 0 && len(mc.Exemplars) > reservoirSize {
+		rand.Shuffle(len(mc.Exemplars), func(i, j int) { mc.Exemplars[i], mc.Exemplars[j] = mc.Exemplars[j], mc.Exemplars[i] })
+		mc.Exemplars = mc.Exemplars[:reservoirSize]
+	}
+}
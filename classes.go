@@ -0,0 +1,264 @@
+package microClustering
+
+import (
+	"fmt"
+	"math"
+)
+
+/*
+  Gestion des classes à l'exécution
+
+  - AddClass crée une classe vide, qui reçoit ensuite des mesures par PartialFit
+  - RemoveClass supprime une classe et toutes les données qui lui sont associées, dont son libellé textuel
+  - MergeClasses fusionne deux classes : chaque µC de la classe absorbée est fusionné avec le µC le plus proche
+    qui le contient, ou ajouté tel quel ; les poids, zones et cluster features sont additionnés
+  - RenameClass change le code d'une classe, son libellé textuel suit le nouveau code
+  - l'oubli des classes inactives (Forgetting.Fade) réduit les classes qui ne reçoivent plus de mesures
+    jusqu'à leur disparition
+*/
+
+// AddClass crée la classe 'label' sans µC
+func (c *Classifier) AddClass(label int) error {
+	if _, exists := c.classes[label]; exists {
+		return fmt.Errorf("class %d already exists", label)
+	}
+	c.class(label)
+	c.seen(label)
+	return nil
+}
+
+// RemoveClass supprime la classe 'label', son rayon, son oubli, son libellé textuel et ses mesures en attente dans le
+// buffer de démarrage
+func (c *Classifier) RemoveClass(label int) error {
+	if _, exists := c.classes[label]; !exists {
+		return fmt.Errorf("unknown class %d", label)
+	}
+	delete(c.classes, label)
+	delete(c.ClassRadius, label)
+	delete(c.ClassForgetting, label)
+	delete(c.learned, label)
+	delete(c.lastSeen, label)
+	if c.Labels != nil {
+		c.Labels.retire(label)
+	}
+
+	data, labels := c.warmUpData, c.warmUpLabels
+	c.warmUpData, c.warmUpLabels = nil, nil
+	for i := range data {
+		if labels[i] != label {
+			c.warmUpData = append(c.warmUpData, data[i])
+			c.warmUpLabels = append(c.warmUpLabels, labels[i])
+		}
+	}
+	return nil
+}
+
+// MergeClasses fusionne la classe 'b' dans la classe 'a' puis supprime la classe 'b'.
+// La classe 'a' reçoit le libellé textuel de 'b' si elle n'en a pas.
+func (c *Classifier) MergeClasses(a, b int) error {
+	if a == b {
+		return fmt.Errorf("cannot merge class %d with itself", a)
+	}
+	into, exists := c.classes[a]
+	if !exists {
+		return fmt.Errorf("unknown class %d", a)
+	}
+	from, exists := c.classes[b]
+	if !exists {
+		return fmt.Errorf("unknown class %d", b)
+	}
+	from.mu.RLock()
+	into.mu.Lock()
+	into.absorbClusterer(from)
+	into.mu.Unlock()
+	from.mu.RUnlock()
+
+	// les mesures en attente de la classe 'b' appartiennent désormais à la classe 'a'
+	for i := range c.warmUpLabels {
+		if c.warmUpLabels[i] == b {
+			c.warmUpLabels[i] = a
+		}
+	}
+	if c.lastSeen[b] > c.lastSeen[a] {
+		c.lastSeen[a] = c.lastSeen[b]
+	}
+	if c.Labels != nil {
+		if _, exists := c.Labels.Decode(a); !exists {
+			c.Labels.recode(b, a)
+		}
+	}
+	return c.RemoveClass(b)
+}
+
+// absorbClusterer fusionne les µC de 'other' dans le Clusterer : chaque µC est fusionné avec le µC le plus proche
+// dont la sphère contient son centre, ou ajouté s'il n'en existe pas
+func (c *Clusterer) absorbClusterer(other *Clusterer) {
+	if c.vectorSize == 0 {
+		c.vectorSize = other.vectorSize
+	}
+	for _, mc := range other.mc {
+		newMc := mc.copy()
+		if len(newMc.Zones) != c.zones { // les zones sont redistribuées si leur nombre diffère
+			newMc.Zones = rebinZones(newMc.Zones, c.zones)
+		}
+		nearest := -1
+		min := 0.0
+		for i := range c.mc {
+			d := c.distance(c.mc[i].Center, mc.Center)
+			if d <= c.radiusOf(c.mc[i]) && (nearest < 0 || d < min) {
+				nearest, min = i, d
+			}
+		}
+		if nearest >= 0 {
+			c.mc[nearest].merge(newMc, c.ReservoirSize)
+			c.updateRadius(c.mc[nearest])
+			continue
+		}
+		c.mc = append(c.mc, newMc)
+	}
+	if other.tick > c.tick {
+		c.tick = other.tick
+	}
+	if c.MaxMicroClusters > 0 && len(c.mc) > c.MaxMicroClusters {
+		c.enforceCapacity()
+	}
+	c.updateStats()
+}
+
+// rebinZones répartit les mesures des zones 'zones' entre 'n' zones de même rayon extérieur, les mesures d'une zone
+// étant supposées uniformément réparties sur son épaisseur. Le nombre total de mesures est conservé.
+func rebinZones(zones []int, n int) []int {
+	// nombre cumulé de mesures à la fraction 'x' du rayon
+	cumulated := func(x float64) float64 {
+		total := 0.0
+		for z, count := range zones {
+			total += float64(count) * math.Max(0, math.Min(1, x*float64(len(zones))-float64(z)))
+		}
+		return total
+	}
+	rebinned := make([]int, n)
+	for z := range rebinned {
+		rebinned[z] = int(math.Round(cumulated(float64(z+1)/float64(n))) - math.Round(cumulated(float64(z)/float64(n))))
+	}
+	return rebinned
+}
+
+// copy renvoie une copie du µC ne partageant aucune donnée avec lui
+func (mc *microcluster) copy() *microcluster {
+	newMc := *mc
+	newMc.Center = append([]float64{}, mc.Center...)
+	newMc.Zones = append([]int{}, mc.Zones...)
+	if mc.LS != nil {
+		newMc.LS = append([]float64{}, mc.LS...)
+		newMc.SS = append([]float64{}, mc.SS...)
+	}
+	if mc.Target != nil {
+		target := *mc.Target
+		newMc.Target = &target
+	}
+	if mc.Labels != nil {
+		newMc.Labels = make(map[int]float64)
+		for label, n := range mc.Labels {
+			newMc.Labels[label] = n
+		}
+	}
+	newMc.Exemplars = append([]Exemplar{}, mc.Exemplars...)
+	return &newMc
+}
+
+// RenameClass change le libellé de la classe 'old' en 'new'
+func (c *Classifier) RenameClass(old, new int) error {
+	cl, exists := c.classes[old]
+	if !exists {
+		return fmt.Errorf("unknown class %d", old)
+	}
+	if _, exists := c.classes[new]; exists {
+		return fmt.Errorf("class %d already exists", new)
+	}
+	delete(c.classes, old)
+	c.classes[new] = cl
+	if cl.Drift != nil {
+		cl.Drift.class = new
+	}
+
+	if r, exists := c.ClassRadius[old]; exists {
+		delete(c.ClassRadius, old)
+		c.ClassRadius[new] = r
+	}
+	if f, exists := c.ClassForgetting[old]; exists {
+		delete(c.ClassForgetting, old)
+		c.ClassForgetting[new] = f
+	}
+	if n, exists := c.learned[old]; exists {
+		delete(c.learned, old)
+		c.learned[new] = n
+	}
+	if t, exists := c.lastSeen[old]; exists {
+		delete(c.lastSeen, old)
+		c.lastSeen[new] = t
+	}
+	for i := range c.warmUpLabels {
+		if c.warmUpLabels[i] == old {
+			c.warmUpLabels[i] = new
+		}
+	}
+	if c.Labels != nil {
+		c.Labels.recode(old, new)
+	}
+	return nil
+}
+
+// seen note que la classe 'label' vient de recevoir une mesure
+func (c *Classifier) seen(label int) {
+	if c.lastSeen == nil {
+		c.lastSeen = make(map[int]int64)
+	}
+	c.lastSeen[label] = c.tick
+}
+
+// forgetting renvoie l'oubli appliqué à la classe 'label'
+func (c *Classifier) forgetting(label int) Forgetting {
+	if f, exists := c.ClassForgetting[label]; exists {
+		return f
+	}
+	return c.Forgetting
+}
+
+// fade applique l'oubli aux classes n'ayant reçu aucune mesure depuis 'Fade' mesures du flux.
+// Une classe trop petite pour que l'oubli supprime encore une mesure est supprimée.
+func (c *Classifier) fade() {
+	for _, label := range c.labels() {
+		f := c.forgetting(label)
+		last, exists := c.lastSeen[label]
+		if !exists { // classe importée sans date d'activité : considérée comme active
+			c.seen(label)
+			continue
+		}
+		if f.Fade <= 0 || f.Pct <= 0 || c.tick-last < int64(f.Fade) {
+			continue
+		}
+		c.seen(label) // prochain oubli dans 'Fade' mesures
+		cl := c.classes[label]
+		proba := f.Proba
+		if proba <= 0 {
+			proba = 1
+		}
+		cl.mu.Lock()
+		if f.Pct*float64(cl.totalWeight()) >= 1 {
+			cl.randomDelete(f.Pct, proba)
+		}
+		faded := f.Pct*float64(cl.totalWeight()) < 1
+		cl.mu.Unlock()
+		if faded {
+			c.RemoveClass(label)
+		}
+	}
+}
+
+// totalWeight renvoie le nombre de mesures contenues dans les µC
+func (c *Clusterer) totalWeight() (total int) {
+	for _, mc := range c.mc {
+		total += mc.Weight
+	}
+	return total
+}
@@ -0,0 +1,180 @@
+package microClustering
+
+import (
+	"testing"
+)
+
+func TestClassManagement(t *testing.T) {
+	X, Y := blobs(100, [][]float64{{0, 0}, {5, 0}, {0, 5}}, 0.5)
+	c := NewClassifier(0, 1, 1, 3, 3)
+	if err := c.FitXY(X, Y); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.AddClass(1); err == nil {
+		t.Error("adding an existing class should fail")
+	}
+	if err := c.AddClass(7); err != nil {
+		t.Fatal(err)
+	}
+	c.PartialFit([]float64{5, 5}, 7)
+	if got := c.KNN([][]float64{{5, 5}}, 1)[0]; got != 7 {
+		t.Errorf("point of the new class predicted %d", got)
+	}
+
+	// fusion : poids et cluster features additionnés
+	weight := c.classes[1].totalWeight() + c.classes[2].totalWeight()
+	if err := c.MergeClasses(1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := c.classes[2]; exists {
+		t.Error("merged class should be removed")
+	}
+	if got := c.classes[1].totalWeight(); got != weight {
+		t.Errorf("merged weight %d, expected %d", got, weight)
+	}
+	if got := c.KNN([][]float64{{0, 5}}, 1)[0]; got != 1 {
+		t.Errorf("point of the merged class predicted %d", got)
+	}
+
+	if err := c.RenameClass(1, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.RenameClass(3, 0); err == nil {
+		t.Error("renaming to an existing class should fail")
+	}
+	if err := c.RemoveClass(7); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.RemoveClass(7); err == nil {
+		t.Error("removing an unknown class should fail")
+	}
+
+	// les opérations sont conservées à l'export
+	data, err := c.ToJson()
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported, err := NewClassifierFromJson(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels := imported.labels()
+	if len(labels) != 2 || labels[0] != 0 || labels[1] != 3 {
+		t.Errorf("classes after import %v, expected [0 3]", labels)
+	}
+	if got := imported.classes[3].totalWeight(); got != weight {
+		t.Errorf("weight after import %d, expected %d", got, weight)
+	}
+}
+
+func TestClassFading(t *testing.T) {
+	c := NewClassifier(0, 1, 1, 1, 3)
+	c.Forgetting = Forgetting{Pct: 0.5, Fade: 10}
+	for i := 0; i < 50; i++ {
+		c.PartialFit([]float64{0, 0}, 0)
+		c.PartialFit([]float64{5, 5}, 1)
+	}
+	// la classe 1 ne reçoit plus de mesures
+	n := 0
+	for ; n < 200; n++ {
+		c.PartialFit([]float64{0, 0}, 0)
+		if _, exists := c.classes[1]; !exists {
+			n++
+			break
+		}
+	}
+	if _, exists := c.classes[1]; exists {
+		t.Errorf("inactive class should have faded out, weight %d", c.classes[1].totalWeight())
+	}
+	if got := c.classes[0].totalWeight(); got != 50+n {
+		t.Errorf("active class weight %d, expected %d", got, 50+n)
+	}
+}
+
+func TestFadeFittedClass(t *testing.T) {
+	c := NewClassifier(0, 1, 1, 1, 3)
+	c.Forgetting = Forgetting{Pct: 0.5, Fade: 10}
+	for i := 0; i < 50; i++ {
+		c.PartialFit([]float64{0, 0}, 0)
+	}
+	// une classe apprise par FitXY après 50 mesures du flux n'est pas inactive depuis le début du flux
+	if err := c.FitXY([][]float64{{5, 5}, {5, 5}, {5, 5}, {5, 5}}, []int{1, 1, 1, 1}); err != nil {
+		t.Fatal(err)
+	}
+	c.PartialFit([]float64{0, 0}, 0)
+	if cl, exists := c.classes[1]; !exists || cl.totalWeight() != 4 {
+		t.Error("class learned by FitXY faded immediately")
+	}
+
+	// classe sans date d'activité (export antérieur) : considérée comme active
+	delete(c.lastSeen, 1)
+	c.PartialFit([]float64{0, 0}, 0)
+	if cl, exists := c.classes[1]; !exists || cl.totalWeight() != 4 {
+		t.Error("class without activity date faded")
+	}
+}
+
+func TestClassManagementLabels(t *testing.T) {
+	X, _ := blobs(20, [][]float64{{0, 0}, {5, 0}, {0, 5}, {5, 5}}, 0.5)
+	labels := []string{}
+	for i := range X {
+		labels = append(labels, []string{"a", "b", "c", "d"}[i%4])
+	}
+	c := NewClassifier(0, 1, 1, 1, 3)
+	if err := c.FitXYStrings(X, labels); err != nil {
+		t.Fatal(err)
+	}
+
+	// le libellé suit le nouveau code de la classe
+	if err := c.RenameClass(1, 7); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.KNNStrings([][]float64{{5, 0}}, 1)[0]; got != "b" {
+		t.Errorf("renamed class predicted %q", got)
+	}
+	if code, _ := c.Labels.Code("b"); code != 7 {
+		t.Errorf("renamed label code %d, expected 7", code)
+	}
+	if _, exists := c.Labels.Decode(1); exists {
+		t.Error("old code of a renamed class should have no label")
+	}
+
+	// la classe fusionnée perd son libellé, la classe supprimée aussi
+	if err := c.MergeClasses(0, 2); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.KNNStrings([][]float64{{0, 5}}, 1)[0]; got != "a" {
+		t.Errorf("merged class predicted %q", got)
+	}
+	if err := c.RemoveClass(3); err != nil {
+		t.Fatal(err)
+	}
+	for _, label := range []string{"c", "d"} {
+		if _, exists := c.Labels.Code(label); exists {
+			t.Errorf("label %q of a removed class is still encoded", label)
+		}
+	}
+
+	// un nouveau libellé ne réutilise pas un code retiré
+	if code, err := c.Labels.Encode("e"); err != nil || code != 8 {
+		t.Errorf("new label code %d, expected 8 (%v)", code, err)
+	}
+
+	data, err := c.ToJson()
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported, err := NewClassifierFromJson(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for code, expected := range map[int]string{0: "a", 7: "b", 8: "e"} {
+		if label, _ := imported.Labels.Decode(code); label != expected {
+			t.Errorf("code %d decoded as %q after import, expected %q", code, label, expected)
+		}
+	}
+	if _, exists := imported.Labels.Decode(2); exists {
+		t.Error("retired code decoded after import")
+	}
+}
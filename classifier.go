@@ -3,11 +3,13 @@ package microClustering
 import (
 	"fmt"
 	"math"
-	"math/rand"
 	"sort"
+	"sync/atomic"
 )
 
 type Classifier struct {
+	rejected int64 // nombre de prédictions rejetées, modifié par sync/atomic (premier champ : alignement 64 bits)
+
 	classes       map[int]*Clusterer //map avec un clusterer par classe. Le label de la classe est obligatoirement un int
 	Radius        float64
 	threshold     int     //Seuls les µC dont la taille dépasse le seuil de prise en compte seront utilisés pour la génération du jeu de données
@@ -15,7 +17,40 @@ type Classifier struct {
 	labelID       int
 	Verbose       int
 	zones         int
-	CheckOutliers bool // TODO: si un point est un outlier pour l'ensemble des classe alors renvoie une classe -1
+	CheckOutliers bool // si un point est un outlier pour l'ensemble des classes alors renvoie la classe UnknownLabel
+
+	// estimation du rayon
+	PerClassRadius   bool             // estime un rayon propre à chaque classe au lieu d'un rayon global
+	ClassRadius      map[int]float64  // rayon de chaque classe, fourni par l'appelant ou estimé (PerClassRadius)
+	RadiusEstimation RadiusEstimation // stratégie d'estimation du rayon
+	RadiusQuantile   float64          // quantile des distances au plus proche voisin (EstimateQuantileNN, 0.9 par défaut)
+	TargetMC         int              // nombre de µC visé par classe (EstimateTargetMC)
+
+	// prédiction
+	Decision     DecisionRule // règle de décision
+	Weighting    Weighting    // pondération du vote des µC voisins
+	UnknownLabel int          // classe renvoyée pour les prédictions rejetées (-1 par défaut)
+	RejectMargin float64      // rejette la prédiction si l'écart de probabilité entre les deux classes les plus probables est inférieur (0 : désactivé)
+
+	distFunction string // nom de la fonction distance utilisée par les classes (vide : fonction globale)
+
+	Labels *LabelEncoder // correspondance entre les libellés textuels et les classes (FitXYStrings)
+
+	Seed int64 // graine du générateur aléatoire de Generate et GenerateQuotas (0 : graine différente à chaque génération)
+
+	// apprentissage incrémental
+	WarmUp          int                // taille du buffer de démarrage utilisé pour estimer le rayon (100 par défaut)
+	Forgetting      Forgetting         // oubli appliqué par défaut à chaque classe
+	ClassForgetting map[int]Forgetting // oubli propre à certaines classes
+	warmUpData      [][]float64        // buffer de démarrage
+	warmUpLabels    []int              // classes des mesures du buffer de démarrage
+	learned         map[int]int        // nombre de mesures apprises par classe depuis le dernier oubli
+	tick            int64              // nombre de mesures apprises par PartialFit
+	lastSeen        map[int]int64      // valeur de tick lors de la dernière mesure apprise par chaque classe
+
+	// détection de dérive
+	Drift      *DriftMonitor                 // dérive du flux : erreur de prédiction observée par ObservePrediction
+	ClassDrift func(label int) *DriftMonitor // crée le DriftMonitor de chaque nouvelle classe (nil : pas de détection)
 }
 
 func NewClassifier(labelId int, radius float64, threshold int, zones int, outlier float64) *Classifier {
@@ -26,135 +61,75 @@ func NewClassifier(labelId int, radius float64, threshold int, zones int, outlie
 	newClassifier.threshold = threshold
 	newClassifier.outlier = outlier
 	newClassifier.Radius = radius
+	newClassifier.UnknownLabel = -1
 	return &newClassifier
 }
 
-var (
-	labelId int
-)
-
-//Définition d'un type byLabel permettant de trier les données sur la colonne label
-type sortByLabel [][]float64
-
-func (m sortByLabel) Len() int { return len(m) }
-func (m sortByLabel) Less(i, j int) bool {
-	return m[i][labelId] < m[j][labelId]
-}
-func (m sortByLabel) Swap(i, j int) { m[i], m[j] = m[j], m[i] }
-
 //Fit réalise l'apprentissage des données 'X' dont le label est précisé dans 'Y'
+// Les données de l'appelant ne sont pas modifiées
 func (c *Classifier) FitXY(X [][]float64, Y []int) error {
 	if len(X) != len(Y) {
 		return fmt.Errorf("data and label mismatch")
 	}
-
-	data := [][]float64{}
+	if len(X) == 0 {
+		return nil
+	}
 
 	//positionne le libellé sur la dernière colonne
 	c.labelID = len(X[0])
 
-	//construit le jeu de données
+	// regroupe les données par label
+	groups := make(map[int][][]float64)
 	for i, v := range X {
-		data = append(data, append(v, float64(Y[i])))
+		vector := make([]float64, len(v))
+		copy(vector, v)
+		groups[Y[i]] = append(groups[Y[i]], vector)
 	}
 
-	c.Fit(data)
-
-	return nil
+	return c.fitGroups(groups)
 }
 
 //Fit réalise l'apprentissage des données 'data' dont le label est en colonnes 'labelId'
-func (c *Classifier) Fit(data [][]float64) {
-	var (
-		classData [][]float64
-		stdDev    []float64
-		moyenne   []float64
-	)
-	labelId = c.labelID
-
-	// trie les données sur la colonne label
-	m := sortByLabel(data)
-	sort.Sort(&m)
-
-	// traite les données par label
-	labelStart := 0
-	currentLabel := data[0][c.labelID]
-
-	if c.Radius == 0 {
-		// calcule les distances moyennes par classe
-		for i, v := range data {
-			if v[c.labelID] != currentLabel || i == len(data)-1 {
-				if i < len(data)-1 {
-					classData = data[labelStart:i]
-				} else {
-					classData = data[labelStart:]
-				}
-				labelStart = i
-				// calcule des statistiques de distance entre points pour estimer automatiquement la bonne taille de cluster
-
-				distances := []float64{}
-
-				for nb := 0; nb < 100; nb++ {
-					i := rand.Intn(len(classData))
-					min := math.MaxFloat64
-					for j := range classData {
-						if i != j {
-							min = math.Min(min, Distance(classData[i], classData[j]))
-						}
-					}
-					distances = append(distances, min)
-				}
-				_, mean := Minmax(distances)
-				_, std := EcartType(distances)
-				moyenne = append(moyenne, mean)
-				stdDev = append(stdDev, std)
-				currentLabel = v[c.labelID]
+// Les labels doivent être des entiers : un label non entier (1.5) renvoie une erreur au lieu d'être tronqué
+// Les données de l'appelant ne sont pas modifiées
+func (c *Classifier) Fit(data [][]float64) error {
+	// regroupe les données par label
+	groups := make(map[int][][]float64)
+	for _, d := range data {
+		label := d[c.labelID]
+		if label != math.Trunc(label) {
+			return fmt.Errorf("label %v is not an integer", label)
+		}
+		vector := make([]float64, 0, len(d)-1)
+		for i, v := range d { // parcours les colonnes
+			if i != c.labelID { // ajoute toutes les colonnes sauf celle contenant le label
+				vector = append(vector, v)
 			}
 		}
+		groups[int(label)] = append(groups[int(label)], vector)
+	}
 
-		// calcule le rayon en fonction des distances moyennes de chaque classe
-		mean, _ := EcartType(moyenne)
-		_, maxStdDev := Minmax(stdDev)
-		c.Radius = mean + 2*maxStdDev //0.5*minStdDev
-		if c.Verbose > 0 {
-			fmt.Println("=> radius=", c.Radius, " seuil=", c.threshold)
-		}
+	return c.fitGroups(groups)
+}
+
+// fitGroups réalise l'apprentissage des données regroupées par label
+func (c *Classifier) fitGroups(groups map[int][][]float64) error {
+	labels := []int{}
+	for label := range groups {
+		labels = append(labels, label)
 	}
+	sort.Ints(labels)
 
-	// Classification
-	labelStart = 0
-	currentLabel = data[0][c.labelID]
-	for i, v := range data { // Parcours les données
-		if v[c.labelID] != currentLabel || i == len(data)-1 { //Fin d'une classe
-			if i < len(data)-1 {
-				classData = data[labelStart:i]
-			} else {
-				classData = data[labelStart:]
-			}
-			labelStart = i
-			dataFragment := [][]float64{}
-
-			for _, d := range classData { // parcours le jeu de données
-				vector := []float64{}
-				for i, v := range d { // parcours les colonnes
-					if i != c.labelID { // ajoute toutes les colonnes sauf celle contenant le label
-						vector = append(vector, v)
-					}
-				}
-				dataFragment = append(dataFragment, vector)
-			}
+	if err := c.estimateRadii(groups); err != nil {
+		return err
+	}
 
-			cl, exists := c.classes[int(currentLabel)]
-			if !exists {
-				cl = NewClusterer(c.Radius, c.threshold, c.zones, c.outlier)
-				c.classes[int(currentLabel)] = cl
-			}
-			cl.Add(dataFragment)
+	// Classification
+	for _, label := range labels {
+		c.class(label).Add(groups[label])
 
-			if c.Verbose > 0 {
-				fmt.Println(" Fit ", len(dataFragment))
-			}
-			currentLabel = v[c.labelID]
+		if c.Verbose > 0 {
+			fmt.Println(" Fit ", len(groups[label]))
 		}
 	}
 	//Affiche les statistiques des classes
@@ -163,59 +138,149 @@ func (c *Classifier) Fit(data [][]float64) {
 			fmt.Println("classe : ", key, " µC=", cl.CountMC())
 		}
 	}
+	return nil
+}
+
+// SetDistanceFunction définit la fonction distance utilisée par les classes créées ensuite et par l'estimation du rayon,
+// indépendamment de la fonction distance globale
+func (c *Classifier) SetDistanceFunction(name string) error {
+	if _, exists := distanceFunctions[name]; !exists {
+		return fmt.Errorf("unknown distance function %q", name)
+	}
+	c.distFunction = name
+	return nil
+}
+
+// distanceFunc renvoie la fonction distance du classifieur
+func (c *Classifier) distanceFunc() DistanceFunc {
+	if f, exists := distanceFunctions[c.distFunction]; exists {
+		return f
+	}
+	return Distance
+}
+
+// class renvoie le Clusterer de la classe 'label', créé s'il n'existe pas encore
+func (c *Classifier) class(label int) *Clusterer {
+	cl, exists := c.classes[label]
+	if !exists {
+		cl = NewClusterer(c.radiusFor(label), c.threshold, c.zones, c.outlier)
+		if c.distFunction != "" {
+			cl.SetDistanceFunction(c.distFunction)
+		}
+		if c.ClassDrift != nil {
+			if cl.Drift = c.ClassDrift(label); cl.Drift != nil {
+				cl.Drift.class = label
+			}
+		}
+		c.classes[label] = cl
+		c.seen(label) // une nouvelle classe est active, même apprise par Fit
+	}
+	return cl
+}
+
+// nnStats calcule, sur un échantillon de 100 points, la plus grande distance au plus proche voisin et l'écart-type de ces distances
+func nnStats(classData [][]float64, distance DistanceFunc) (max, std float64) {
+	distances := nnDistances(classData, distance)
+	_, max = Minmax(distances)
+	_, std = EcartType(distances)
+	return max, std
+}
+
+// radiusFromStats calcule le rayon en fonction des distances moyennes de chaque classe
+func radiusFromStats(moyenne, stdDev []float64) float64 {
+	mean, _ := EcartType(moyenne)
+	_, maxStdDev := Minmax(stdDev)
+	return mean + 2*maxStdDev //0.5*minStdDev
 }
 
 //Knn renvoie les libellés des classes les plus proches en utilisant l'algorithme k-Nearest Neightbors
+// ou la règle de décision choisie (Decision)
+// En cas d'égalité, la classe retenue est celle du µC le plus proche puis le plus petit libellé
+// Les prédictions rejetées (voir CheckOutliers et RejectMargin) renvoient UnknownLabel
 func (c *Classifier) KNN(x [][]float64, k int) (y []int) {
 	y = []int{}
 	// traite chaque vecteur de données
 	for _, vector := range x {
-		nearestNeighbors := neighborList{} // liste des µC les plus proches
-		// recherche les k NN de chaque classe
-		for key, clusterer := range c.classes {
-			nb := clusterer.KNN(vector, k)
-			for i := range nb {
-				nb[i].class = key
-				//nb[i].distance = nb[i].distance /* / float64(nb[i].weight)*/ //pondère la distance en fonction du poids du cluster
-				nearestNeighbors = append(nearestNeighbors, nb[i])
-			}
+		proba, nearestNeighbors := c.predict(vector, k)
+		if c.reject(vector, proba) {
+			atomic.AddInt64(&c.rejected, 1) // les prédictions peuvent être concurrentes
+			y = append(y, c.UnknownLabel)
+			continue
 		}
+		y = append(y, bestClass(proba, nearestNeighbors))
+	}
+	return y
+}
 
-		//trie par distance
-		sort.Sort(nearestNeighbors)
-
-		// recherche la k ieme distance (plusieurs points peuvent être à la même distance)
-		toK := 0
-		kId := 0
-		lastValue := -1.0
-		for i := range nearestNeighbors {
-			if nearestNeighbors[i].distance != lastValue {
-				lastValue = nearestNeighbors[i].distance
-				toK++
-			}
-			if toK == k {
-				kId = i + 1
-				break
+// GenerateStrategy définit la répartition des classes dans un jeu de données généré par Classifier.Generate
+type GenerateStrategy int
+
+const (
+	// Proportional respecte les proportions des classes apprises
+	Proportional GenerateStrategy = iota
+	// Balanced génère le même nombre de points pour chaque classe (sur-échantillonnage des classes rares)
+	Balanced
+)
+
+// Generate génère un jeu de données de 'size' éléments à partir des µC de chaque classe.
+// La répartition entre classes dépend de la stratégie choisie.
+// Les données sont renvoyées au format attendu par FitXY : X contient les vecteurs et Y les libellés
+func (c *Classifier) Generate(size int, strategy GenerateStrategy) (X [][]float64, Y []int) {
+	quotas := make(map[int]int)
+	labels := c.generableLabels()
+	if len(labels) == 0 {
+		return X, Y
+	}
+
+	switch strategy {
+	case Balanced:
+		for i, label := range labels {
+			quotas[label] = size / len(labels)
+			if i < size%len(labels) { // répartit le reste sur les premières classes
+				quotas[label]++
 			}
 		}
-		// recherche la classe la plus représentée
-		nearestClass := 0
-		nbMax := -1.0
-		for key := range c.classes {
-			nb := 0.0
-
-			for _, v := range nearestNeighbors[:kId] {
-				if v.class == key {
-					nb += 1 //v.distance / float64(v.weight)
-				}
-			}
-			if nb > nbMax {
-				nbMax = nb
-				nearestClass = key
-			}
+	default:
+		totalSize := 0
+		for _, label := range labels {
+			totalSize += c.classes[label].Size()
+		}
+		for _, label := range labels {
+			quotas[label] = int(math.Round(float64(size*c.classes[label].Size()) / float64(totalSize)))
+		}
+	}
+	return c.GenerateQuotas(quotas)
+}
+
+// GenerateQuotas génère pour chaque classe le nombre d'éléments précisé dans 'quotas'.
+// Les classes inconnues ou ne contenant aucun µC représentatif sont ignorées
+func (c *Classifier) GenerateQuotas(quotas map[int]int) (X [][]float64, Y []int) {
+	rnd := newRand(c.Seed)
+	for _, label := range c.generableLabels() {
+		size, exists := quotas[label]
+		if !exists || size <= 0 {
+			continue
+		}
+		data := c.classes[label].generate(size, rnd)
+		if len(data) > size { // Clusterer.Generate peut renvoyer un peu plus de points que demandé
+			rnd.Shuffle(len(data), func(i, j int) { data[i], data[j] = data[j], data[i] })
+			data = data[:size]
+		}
+		for _, v := range data {
+			X = append(X, v)
+			Y = append(Y, label)
 		}
+	}
+	return X, Y
+}
 
-		y = append(y, nearestClass)
+// generableLabels renvoie, triés, les libellés des classes possédant au moins un µC représentatif
+func (c *Classifier) generableLabels() (labels []int) {
+	for label, cl := range c.classes {
+		if cl.Size() > 0 {
+			labels = append(labels, label)
+		}
 	}
-	return y
+	sort.Ints(labels)
+	return labels
 }
@@ -2,6 +2,8 @@ package microClustering
 
 import (
 	"fmt"
+	"math"
+	"sync"
 	"testing"
 )
 
@@ -48,3 +50,399 @@ func TestClassifier(t *testing.T) {
 	fmt.Println("y2=", y2)
 
 }
+
+func TestClassifierGenerate(t *testing.T) {
+
+	data := [][]float64{{3.0, 2.0}, {3.1, 2.1}, {3.0, 2.1}, {2.9, 1.9}, {3.0, 1.9}, {2.0, 4.0}, {2.1, 4.1}, {1.9, 4.1},
+		{9.0, 2.0}, {9.1, 2.1}, {8.9, 2.1}}
+	labels := []int{1, 1, 1, 1, 1, 1, 1, 1, 2, 2, 2}
+
+	SetDistanceFunction("euclidian")
+	c := NewClassifier(2, 0.5, 1, 2, 3.0)
+	if err := c.FitXY(data, labels); err != nil {
+		t.Fatal(err)
+	}
+
+	count := func(Y []int) map[int]int {
+		nb := make(map[int]int)
+		for _, y := range Y {
+			nb[y]++
+		}
+		return nb
+	}
+
+	X, Y := c.Generate(100, Balanced)
+	if len(X) != len(Y) {
+		t.Fatalf("X and Y mismatch : %d != %d", len(X), len(Y))
+	}
+	if nb := count(Y); nb[1] != 50 || nb[2] != 50 {
+		t.Errorf("balanced : %v", nb)
+	}
+
+	_, Y = c.Generate(110, Proportional)
+	if nb := count(Y); nb[1] <= nb[2] {
+		t.Errorf("proportional : %v", nb)
+	}
+
+	_, Y = c.GenerateQuotas(map[int]int{2: 7})
+	if nb := count(Y); nb[1] != 0 || nb[2] != 7 {
+		t.Errorf("quotas : %v", nb)
+	}
+
+	// le jeu généré doit pouvoir être réappris tel quel
+	X, Y = c.Generate(20, Balanced)
+	c2 := NewClassifier(2, 0.5, 1, 2, 3.0)
+	if err := c2.FitXY(X, Y); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPartialFit(t *testing.T) {
+
+	SetDistanceFunction("euclidian")
+	c := NewClassifier(2, 0, 1, 2, 3.0)
+	c.WarmUp = 40
+	c.ClassForgetting = map[int]Forgetting{2: {Every: 50, Pct: 0.5}}
+
+	// flux alterné de deux classes
+	for i := 0; i < 200; i++ {
+		x := []float64{float64(i%5) * 0.1, float64(i%7) * 0.1}
+		label := 1
+		if i%2 == 1 {
+			x[0] += 5
+			label = 2
+		}
+		c.PartialFit(x, label)
+		if i < 39 && c.Radius != 0 {
+			t.Fatal("radius estimated before the end of the warm-up")
+		}
+	}
+	if c.Radius == 0 {
+		t.Fatal("radius not estimated")
+	}
+	fmt.Println("radius=", c.Radius, " µC=", c.classes[1].CountMC(), c.classes[2].CountMC())
+
+	if c.classes[2].Size() >= c.classes[1].Size() {
+		t.Errorf("forgetting not applied to class 2 : %d >= %d", c.classes[2].Size(), c.classes[1].Size())
+	}
+
+	y := c.KNN([][]float64{{0.2, 0.3}, {5.2, 0.3}}, 1)
+	if y[0] != 1 || y[1] != 2 {
+		t.Errorf("KNN after PartialFit : %v", y)
+	}
+
+	if err := c.PartialFitXY([][]float64{{0, 0}}, []int{1, 2}); err == nil {
+		t.Error("data and label mismatch not detected")
+	}
+}
+
+func TestWarmUpFailure(t *testing.T) {
+	c := NewClassifier(0, 0, 1, 1, 3)
+	c.WarmUp = 3
+
+	// une mesure par classe : le rayon ne peut pas être estimé
+	for y := 0; y < 3; y++ {
+		err := c.PartialFit([]float64{float64(y), 0}, y)
+		if y < 2 && err != nil || y == 2 && err == nil {
+			t.Errorf("measure %d : error %v", y, err)
+		}
+	}
+	// l'estimation n'est retentée qu'après 'WarmUp' nouvelles mesures
+	for i, y := range []int{0, 1, 2} {
+		err := c.PartialFit([]float64{float64(y), 1}, y)
+		if i < 2 && (err != nil || len(c.warmUpData) != 4+i) {
+			t.Errorf("flush retried before the buffer grew by WarmUp : %v, %d buffered", err, len(c.warmUpData))
+		}
+		if i == 2 && err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.Radius == 0 || len(c.warmUpData) != 0 || len(c.classes) != 3 {
+		t.Errorf("warm-up not flushed : radius %g, %d buffered, %d classes", c.Radius, len(c.warmUpData), len(c.classes))
+	}
+}
+
+func TestPredictProba(t *testing.T) {
+
+	data := [][]float64{{0, 0}, {0.1, 0}, {0, 0.1}, {5, 5}, {5.1, 5}, {5, 5.1}, {10, 0}, {10.1, 0}}
+	labels := []int{1, 1, 1, 2, 2, 2, 3, 3}
+
+	SetDistanceFunction("euclidian")
+	for _, weighting := range []Weighting{UniformWeighting, InverseDistanceWeighting, MCWeightWeighting} {
+		c := NewClassifier(2, 0.5, 1, 1, 3.0)
+		c.Weighting = weighting
+		if err := c.FitXY(data, labels); err != nil {
+			t.Fatal(err)
+		}
+
+		proba := c.PredictProba([]float64{0.2, 0.2}, 2)
+		sum := 0.0
+		for _, p := range proba {
+			sum += p
+		}
+		if math.Abs(sum-1) > 1e-9 || len(proba) != 3 {
+			t.Errorf("weighting %d : invalid probabilities %v", weighting, proba)
+		}
+		if proba[1] < proba[2] || proba[1] < proba[3] {
+			t.Errorf("weighting %d : class 1 should be the most probable %v", weighting, proba)
+		}
+
+		classes, matrix := c.PredictProbaMatrix([][]float64{{0.2, 0.2}, {5, 5}}, 2)
+		if len(classes) != 3 || len(matrix) != 2 || matrix[1][1] < matrix[1][0] {
+			t.Errorf("weighting %d : invalid matrix %v %v", weighting, classes, matrix)
+		}
+	}
+
+	// égalité parfaite : le résultat doit être stable
+	c := NewClassifier(2, 0.5, 1, 1, 3.0)
+	c.FitXY([][]float64{{0, 0}, {0, 0}, {2, 0}, {2, 0}}, []int{7, 7, 3, 3})
+	for i := 0; i < 20; i++ {
+		if y := c.KNN([][]float64{{1, 0}}, 2); y[0] != 3 {
+			t.Fatalf("non deterministic tie-breaking : %v", y)
+		}
+	}
+}
+
+func TestReject(t *testing.T) {
+
+	data := [][]float64{{0, 0}, {0.1, 0}, {0, 0.1}, {5, 5}, {5.1, 5}, {5, 5.1}}
+	labels := []int{1, 1, 1, 2, 2, 2}
+
+	SetDistanceFunction("euclidian")
+	c := NewClassifier(2, 0.5, 1, 1, 3.0)
+	c.CheckOutliers = true
+	if err := c.FitXY(data, labels); err != nil {
+		t.Fatal(err)
+	}
+
+	y := c.KNN([][]float64{{0.1, 0.1}, {20, 20}}, 1)
+	if y[0] != 1 || y[1] != c.UnknownLabel || c.UnknownLabel != -1 {
+		t.Errorf("outlier not rejected : %v", y)
+	}
+	if c.Rejected() != 1 {
+		t.Errorf("rejected=%d", c.Rejected())
+	}
+
+	// point à égale distance des deux classes
+	c.CheckOutliers = false
+	c.RejectMargin = 0.2
+	c.UnknownLabel = 99
+	c.Weighting = InverseDistanceWeighting
+	y = c.KNN([][]float64{{2.5, 2.5}, {5, 5}}, 2)
+	if y[0] != 99 || y[1] == 99 {
+		t.Errorf("margin rejection : %v", y)
+	}
+	if c.Rejected() != 2 {
+		t.Errorf("rejected=%d", c.Rejected())
+	}
+
+	// prédictions concurrentes (à lancer avec go test -race)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.KNN([][]float64{{2.5, 2.5}}, 2)
+		}()
+	}
+	wg.Wait()
+	if c.Rejected() != 6 {
+		t.Errorf("rejected=%d after concurrent predictions", c.Rejected())
+	}
+
+	js, _ := c.ToJson()
+	c2, err := NewClassifierFromJson(js)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c2.UnknownLabel != 99 || c2.RejectMargin != 0.2 || c2.Rejected() != 6 {
+		t.Error("reject options not persisted")
+	}
+}
+
+func TestStringLabels(t *testing.T) {
+
+	data := [][]float64{{0, 0}, {0.1, 0}, {0, 0.1}, {5, 5}, {5.1, 5}, {5, 5.1}}
+	labels := []string{"api", "api", "api", "db", "db", "db"}
+
+	SetDistanceFunction("euclidian")
+	c := NewClassifier(0, 0.5, 1, 1, 3.0)
+	if err := c.FitXYStrings(data, labels); err != nil {
+		t.Fatal(err)
+	}
+
+	y := c.KNNStrings([][]float64{{0.1, 0.1}, {5, 5}}, 1)
+	if y[0] != "api" || y[1] != "db" {
+		t.Errorf("KNNStrings : %v", y)
+	}
+	if p := c.PredictProbaStrings([]float64{5, 5}, 1); p["db"] != 1 {
+		t.Errorf("PredictProbaStrings : %v", p)
+	}
+
+	js, _ := c.ToJson()
+	c2, err := NewClassifierFromJson(js)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if y := c2.KNNStrings([][]float64{{5, 5}}, 1); y[0] != "db" {
+		t.Errorf("label mapping not persisted : %v", y)
+	}
+
+	// le libellé vide est refusé sans modifier l'encodeur
+	if err := c.FitXYStrings([][]float64{{1, 1}, {2, 2}}, []string{"cache", ""}); err == nil {
+		t.Error("empty label accepted")
+	}
+	if _, known := c.Labels.Code("cache"); known {
+		t.Error("labels encoded before the empty label was rejected")
+	}
+	if _, err := c.Labels.Encode(""); err == nil {
+		t.Error("empty label encoded")
+	}
+
+	// un label non entier ne doit pas être tronqué
+	if err := c.FitXY([][]float64{{1, 1}}, []int{1}); err != nil {
+		t.Error(err)
+	}
+	if err := c.Fit([][]float64{{1, 1, 1.5}}); err == nil {
+		t.Error("non integer label accepted")
+	}
+}
+
+// TestConcurrentFit doit être lancé avec le détecteur de race conditions : go test -race
+func TestConcurrentFit(t *testing.T) {
+
+	SetDistanceFunction("euclidian")
+
+	var wg sync.WaitGroup
+	for n := 0; n < 8; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+
+			// chaque classifieur utilise une colonne label différente
+			labelID := n % 3
+			data := [][]float64{}
+			for i := 0; i < 60; i++ {
+				row := []float64{float64(i%6) * 0.1, float64(i%4) * 0.1, float64(i%5) * 0.1}
+				label := float64(i % 2)
+				row[0] += label * 5
+				row = append(row[:labelID], append([]float64{label}, row[labelID:]...)...)
+				data = append(data, row)
+			}
+			original := [][]float64{}
+			for _, row := range data {
+				original = append(original, append([]float64{}, row...))
+			}
+
+			c := NewClassifier(labelID, 0, 1, 2, 3.0)
+			if err := c.Fit(data); err != nil {
+				t.Error(err)
+				return
+			}
+			if len(c.classes) != 2 {
+				t.Errorf("classifier %d : %d classes", n, len(c.classes))
+			}
+			for i := range data {
+				for j := range data[i] {
+					if data[i][j] != original[i][j] {
+						t.Errorf("classifier %d : input data modified", n)
+						return
+					}
+				}
+			}
+
+			// lignes partageant le même tableau sous-jacent
+			backing := []float64{0, 0, 5, 0}
+			X := [][]float64{backing[0:2], backing[2:4]}
+			c2 := NewClassifier(0, 0.5, 1, 2, 3.0)
+			if err := c2.FitXY(X[:1], []int{1}); err != nil {
+				t.Error(err)
+			}
+			if X[1][0] != 5 {
+				t.Errorf("classifier %d : FitXY modified its input", n)
+			}
+		}(n)
+	}
+	wg.Wait()
+}
+
+func TestPerClassRadius(t *testing.T) {
+
+	// classe 1 dense, classe 2 clairsemée
+	X := [][]float64{}
+	Y := []int{}
+	for i := 0; i < 50; i++ {
+		X = append(X, []float64{float64(i%10) * 0.01, float64(i/10) * 0.01})
+		Y = append(Y, 1)
+		X = append(X, []float64{10 + float64(i%10), float64(i / 10)})
+		Y = append(Y, 2)
+	}
+
+	SetDistanceFunction("euclidian")
+	for _, estimation := range []RadiusEstimation{EstimateMeanNN, EstimateQuantileNN, EstimateTargetMC} {
+		c := NewClassifier(0, 0, 1, 1, 3.0)
+		c.PerClassRadius = true
+		c.RadiusEstimation = estimation
+		c.TargetMC = 5
+		if err := c.FitXY(X, Y); err != nil {
+			t.Fatal(err)
+		}
+		fmt.Println("estimation", estimation, ":", c.ClassRadius, " µC=", c.classes[1].CountMC(), c.classes[2].CountMC())
+		if c.ClassRadius[1] >= c.ClassRadius[2] {
+			t.Errorf("estimation %d : dense class radius %f >= sparse class radius %f", estimation, c.ClassRadius[1], c.ClassRadius[2])
+		}
+		if estimation == EstimateTargetMC && (c.classes[1].CountMC() > 5 || c.classes[2].CountMC() > 5) {
+			t.Errorf("target µC count not reached : %d %d", c.classes[1].CountMC(), c.classes[2].CountMC())
+		}
+
+		js, _ := c.ToJson()
+		c2, err := NewClassifierFromJson(js)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c2.ClassRadius[2] != c.ClassRadius[2] || c2.classes[2].mcRadius != c.ClassRadius[2] {
+			t.Errorf("estimation %d : class radius not persisted", estimation)
+		}
+	}
+
+	// rayon fourni par l'appelant
+	c := NewClassifier(0, 0, 1, 1, 3.0)
+	c.PerClassRadius = true
+	c.ClassRadius = map[int]float64{2: 3}
+	c.FitXY(X, Y)
+	if c.classes[2].mcRadius != 3 {
+		t.Errorf("supplied class radius ignored : %f", c.classes[2].mcRadius)
+	}
+}
+
+func TestRadiusFallback(t *testing.T) {
+	SetDistanceFunction("euclidian")
+	X := [][]float64{{0, 0}, {0.1, 0}, {0, 0.1}, {0.1, 0.1}, {5, 5}}
+	Y := []int{1, 1, 1, 1, 2}
+
+	// une classe d'une seule mesure reçoit le rayon moyen des classes estimées
+	c := NewClassifier(0, 0, 1, 1, 3.0)
+	c.PerClassRadius = true
+	if err := c.FitXY(X, Y); err != nil {
+		t.Fatal(err)
+	}
+	if c.ClassRadius[2] != c.ClassRadius[1] || c.ClassRadius[2] <= 0 {
+		t.Errorf("class radius %v, expected the estimated radius for the singleton class", c.ClassRadius)
+	}
+	if s := c.OutlierScore([]float64{5, 5}); math.IsNaN(s) {
+		t.Error("outlier score is NaN")
+	}
+
+	// aucune classe ne permet d'estimer un rayon
+	for _, perClass := range []bool{false, true} {
+		c = NewClassifier(0, 0, 1, 1, 3.0)
+		c.PerClassRadius = perClass
+		if err := c.FitXY([][]float64{{0, 0}, {5, 5}}, []int{1, 2}); err == nil {
+			t.Errorf("per class radius %v: fitting singleton classes without radius should fail", perClass)
+		}
+	}
+	c = NewClassifier(0, 0, 1, 1, 3.0)
+	if err := c.FitXY([][]float64{{1, 1}, {1, 1}}, []int{1, 1}); err == nil {
+		t.Error("fitting identical points without radius should fail")
+	}
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	microClustering "github.com/nj-apps/sb4c"
+)
+
+// options regroupe les options des sous-commandes
+type options struct {
+	radius   float64
+	zones    int
+	minSize  int
+	outlier  float64
+	distance string
+	seed     int64
+	label    int
+
+	format string
+	header bool
+	output string
+
+	k        int
+	size     int
+	balanced bool
+}
+
+// newFlagSet crée les options communes à toutes les sous-commandes
+func newFlagSet(name string, o *options) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&o.format, "format", "", "data format: csv, jsonl or binary (default: from the file extension, csv for stdin)")
+	fs.BoolVar(&o.header, "header", false, "the first CSV line is a header")
+	fs.StringVar(&o.output, "o", "", "output file (default: stdout)")
+	return fs
+}
+
+// modelFlags ajoute les paramètres d'apprentissage
+func modelFlags(fs *flag.FlagSet, o *options) {
+	fs.Float64Var(&o.radius, "radius", 0, "µC radius (0: estimated from the data, requires -label)")
+	fs.IntVar(&o.zones, "zones", 1, "number of concentric zones per µC")
+	fs.IntVar(&o.minSize, "minsize", 1, "minimum weight of a representative µC")
+	fs.Float64Var(&o.outlier, "outlier", 3, "outlier threshold, in standard deviations of the µC weights")
+	fs.StringVar(&o.distance, "distance", "euclidian", "distance function")
+	fs.IntVar(&o.label, "label", -1, "column of the class label, numeric or text in CSV (-1: no label)")
+}
+
+// fit apprend les données et écrit le snapshot
+func fit(args []string, stdin io.Reader, stdout io.Writer) error {
+	o := &options{}
+	fs := newFlagSet("fit", o)
+	modelFlags(fs, o)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	m := model{}
+	if o.label < 0 {
+		if o.radius <= 0 {
+			return fmt.Errorf("-radius is required without -label")
+		}
+		data, err := readData(fs.Arg(0), stdin, o)
+		if err != nil {
+			return err
+		}
+		if len(data) == 0 {
+			return fmt.Errorf("no data")
+		}
+		m.clusterer = microClustering.NewClusterer(o.radius, o.minSize, o.zones, o.outlier)
+		if err := m.clusterer.SetDistanceFunction(o.distance); err != nil {
+			return err
+		}
+		m.clusterer.Add(data)
+	} else {
+		X, labels, err := readLabelled(fs.Arg(0), stdin, o)
+		if err != nil {
+			return err
+		}
+		if len(X) == 0 {
+			return fmt.Errorf("no data")
+		}
+		m.classifier = microClustering.NewClassifier(o.label, o.radius, o.minSize, o.zones, o.outlier)
+		if err := m.classifier.SetDistanceFunction(o.distance); err != nil {
+			return err
+		}
+		if err := fitLabels(m.classifier, X, labels, o.label); err != nil {
+			return err
+		}
+	}
+	return o.write(stdout, m.save)
+}
+
+// fitLabels apprend les mesures : des classes toutes numériques sont apprises par Fit, sinon les classes sont des
+// libellés textuels appris par FitXYStrings
+func fitLabels(c *microClustering.Classifier, X [][]float64, labels []string, column int) error {
+	data := make([][]float64, len(X))
+	for i, label := range labels {
+		y, err := strconv.ParseFloat(label, 64)
+		if err != nil {
+			return c.FitXYStrings(X, labels)
+		}
+		data[i] = withLabel(X[i], y, column)
+	}
+	return c.Fit(data)
+}
+
+// predict écrit la classe prédite de chaque mesure
+func predict(args []string, stdin io.Reader, stdout io.Writer) error {
+	o := &options{}
+	fs := newFlagSet("predict", o)
+	fs.IntVar(&o.k, "k", 3, "number of neighbour µC")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	m, err := loadSnapshot(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if m.classifier == nil {
+		return fmt.Errorf("predict requires a classifier snapshot (fit with -label)")
+	}
+	data, err := readData(fs.Arg(1), stdin, o)
+	if err != nil {
+		return err
+	}
+
+	var labels []string
+	if m.classifier.Labels != nil {
+		labels = m.classifier.KNNStrings(data, o.k)
+	} else {
+		for _, y := range m.classifier.KNN(data, o.k) {
+			labels = append(labels, strconv.Itoa(y))
+		}
+	}
+	return o.write(stdout, func(w io.Writer) error {
+		for _, label := range labels {
+			if _, err := fmt.Fprintln(w, label); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// score écrit le score d'outlier de chaque mesure
+func score(args []string, stdin io.Reader, stdout io.Writer) error {
+	o := &options{}
+	fs := newFlagSet("score", o)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	m, err := loadSnapshot(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	data, err := readData(fs.Arg(1), stdin, o)
+	if err != nil {
+		return err
+	}
+	return o.write(stdout, func(w io.Writer) error {
+		for _, x := range data {
+			s := 0.0
+			if m.clusterer != nil {
+				s = m.clusterer.OutlierScore(x)
+			} else {
+				s = m.classifier.OutlierScore(x)
+			}
+			if _, err := fmt.Fprintln(w, strconv.FormatFloat(s, 'g', -1, 64)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// generate écrit un jeu de données synthétique ; pour un Classifier, la classe est ajoutée en colonne -label
+func generate(args []string, stdin io.Reader, stdout io.Writer) error {
+	o := &options{}
+	fs := newFlagSet("generate", o)
+	fs.Int64Var(&o.seed, "seed", 0, "random seed (0: different at each run)")
+	fs.IntVar(&o.size, "n", 1000, "number of points to generate")
+	fs.BoolVar(&o.balanced, "balanced", false, "same number of points for each class")
+	fs.IntVar(&o.label, "label", -1, "column of the class label (-1: last column)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	m, err := loadSnapshot(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	format, err := o.streamFormat(o.output)
+	if err != nil {
+		return err
+	}
+
+	var data [][]float64
+	if m.clusterer != nil {
+		if m.clusterer.CountMC() == 0 {
+			return fmt.Errorf("empty snapshot")
+		}
+		m.clusterer.Seed = o.seed
+		data = m.clusterer.Generate(o.size)
+	} else {
+		strategy := microClustering.Proportional
+		if o.balanced {
+			strategy = microClustering.Balanced
+		}
+		m.classifier.Seed = o.seed
+		X, Y := m.classifier.Generate(o.size, strategy)
+		for i, x := range X {
+			data = append(data, withLabel(x, float64(Y[i]), o.label))
+		}
+	}
+	return o.write(stdout, func(w io.Writer) error {
+		return writeData(w, format, data)
+	})
+}
+
+// withLabel insère la classe 'y' en colonne 'column' de la mesure (en dernière colonne si 'column' est négative)
+func withLabel(x []float64, y float64, column int) []float64 {
+	if column < 0 || column > len(x) {
+		column = len(x)
+	}
+	row := make([]float64, 0, len(x)+1)
+	row = append(row, x[:column]...)
+	row = append(row, y)
+	return append(row, x[column:]...)
+}
+
+// snapshotStats contient les informations du snapshot JSON d'un Clusterer utilisées par stats
+type snapshotStats struct {
+	McRadius float64 `json:"mc_radius"`
+	MinSize  int     `json:"min_size"`
+	Mc       []struct {
+		Weight int `json:"weight"`
+	} `json:"mc_list"`
+}
+
+// stats résume le nombre et le poids des µC du snapshot, par classe pour un Classifier
+func stats(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	data, err := readFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	snapshot := struct {
+		snapshotStats
+		Classes map[int]snapshotStats `json:"classes"`
+	}{}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "class\tµC\trepresentative\tweight\tmin\tmean\tmax\tradius\t")
+	if snapshot.Classes == nil {
+		printStats(w, "-", snapshot.snapshotStats)
+	} else {
+		for _, label := range sortedLabels(snapshot.Classes) {
+			printStats(w, strconv.Itoa(label), snapshot.Classes[label])
+		}
+	}
+	return w.Flush()
+}
+
+// printStats écrit la ligne de statistiques d'un Clusterer
+func printStats(w io.Writer, name string, s snapshotStats) {
+	representative, weight, min, max := 0, 0, 0, 0
+	for i, mc := range s.Mc {
+		if mc.Weight >= s.MinSize {
+			representative++
+		}
+		weight += mc.Weight
+		if i == 0 || mc.Weight < min {
+			min = mc.Weight
+		}
+		if mc.Weight > max {
+			max = mc.Weight
+		}
+	}
+	mean := 0.0
+	if len(s.Mc) > 0 {
+		mean = float64(weight) / float64(len(s.Mc))
+	}
+	fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%.1f\t%d\t%.4g\t\n", name, len(s.Mc), representative, weight, min, mean, max, s.McRadius)
+}
+
+// merge fusionne les snapshots, qui doivent être tous des Clusterer ou tous des Classifier
+func merge(args []string, stdin io.Reader, stdout io.Writer) error {
+	o := &options{}
+	fs := newFlagSet("merge", o)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no snapshot to merge")
+	}
+	merged, err := loadSnapshot(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	for _, path := range fs.Args()[1:] {
+		m, err := loadSnapshot(path)
+		if err != nil {
+			return err
+		}
+		switch {
+		case merged.clusterer != nil && m.clusterer != nil:
+			err = merged.clusterer.Merge(m.clusterer)
+		case merged.classifier != nil && m.classifier != nil:
+			err = merged.classifier.Merge(m.classifier)
+		default:
+			err = fmt.Errorf("cannot merge a clusterer and a classifier snapshot")
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	return o.write(stdout, merged.save)
+}
+
+// write écrit la sortie de la sous-commande dans le fichier -o ou sur 'stdout'
+func (o *options) write(stdout io.Writer, output func(w io.Writer) error) error {
+	if o.output == "" {
+		return output(stdout)
+	}
+	f, err := os.Create(o.output)
+	if err != nil {
+		return err
+	}
+	if err := output(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readFile lit le fichier 'path'
+func readFile(path string) ([]byte, error) {
+	if path == "" {
+		return nil, fmt.Errorf("missing snapshot file")
+	}
+	return ioutil.ReadFile(path)
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	microClustering "github.com/nj-apps/sb4c"
+)
+
+// model contient le Clusterer ou le Classifier d'un snapshot
+type model struct {
+	clusterer  *microClustering.Clusterer
+	classifier *microClustering.Classifier
+}
+
+// loadSnapshot lit un snapshot JSON : un snapshot contenant des classes est celui d'un Classifier
+func loadSnapshot(path string) (m model, err error) {
+	data, err := readFile(path)
+	if err != nil {
+		return m, err
+	}
+	kind := struct {
+		Classes json.RawMessage `json:"classes"`
+	}{}
+	if err := json.Unmarshal(data, &kind); err != nil {
+		return m, fmt.Errorf("%s: %v", path, err)
+	}
+	if kind.Classes != nil {
+		m.classifier, err = microClustering.NewClassifierFromJson(data)
+	} else {
+		m.clusterer, err = microClustering.NewClustererFromJson(data)
+	}
+	if err != nil {
+		return m, fmt.Errorf("%s: %v", path, err)
+	}
+	return m, nil
+}
+
+// save écrit le snapshot JSON du modèle
+func (m model) save(w io.Writer) error {
+	var data []byte
+	var err error
+	if m.clusterer != nil {
+		data, err = m.clusterer.ToJson()
+	} else {
+		data, err = m.classifier.ToJson()
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// formats associe à chaque nom de format et extension de fichier son StreamFormat
+var formats = map[string]microClustering.StreamFormat{
+	"csv":    microClustering.CSVFormat,
+	"jsonl":  microClustering.JSONLinesFormat,
+	"ndjson": microClustering.JSONLinesFormat,
+	"binary": microClustering.BinaryFormat,
+	"bin":    microClustering.BinaryFormat,
+}
+
+// streamFormat renvoie le format -format, ou à défaut celui de l'extension du fichier 'path' (csv par défaut)
+func (o *options) streamFormat(path string) (microClustering.StreamFormat, error) {
+	name := o.format
+	if name == "" {
+		name = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+	if name == "" {
+		return microClustering.CSVFormat, nil
+	}
+	format, exists := formats[strings.ToLower(name)]
+	if !exists {
+		return 0, fmt.Errorf("unknown data format %q", name)
+	}
+	return format, nil
+}
+
+// readData lit toutes les mesures du fichier 'path' (de 'stdin' si 'path' est vide ou vaut "-").
+// Une mesure mal formée est une erreur : chaque ligne de résultat doit correspondre à une mesure lue.
+func readData(path string, stdin io.Reader, o *options) ([][]float64, error) {
+	format, err := o.streamFormat(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := openData(path, stdin)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	errors := make(chan error, 1)
+	s := microClustering.NewStream()
+	s.Header = o.header
+	s.Errors = errors
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rows := make(chan []float64, s.BatchSize)
+	decoded := make(chan error, 1)
+	go func() {
+		decoded <- s.Decode(ctx, r, format, rows)
+	}()
+
+	var data [][]float64
+	for {
+		select {
+		case x, ok := <-rows:
+			if ok {
+				data = append(data, x)
+				continue
+			}
+			if err := <-decoded; err != nil {
+				return nil, err
+			}
+			select {
+			case err := <-errors:
+				return nil, err
+			default:
+				return data, nil
+			}
+		case err := <-errors:
+			cancel()
+			<-decoded
+			return nil, err
+		}
+	}
+}
+
+// openData ouvre le fichier 'path' ('stdin' si 'path' est vide ou vaut "-")
+func openData(path string, stdin io.Reader) (io.ReadCloser, error) {
+	if path == "" || path == "-" {
+		return ioutil.NopCloser(stdin), nil
+	}
+	return os.Open(path)
+}
+
+// readLabelled lit les mesures et la classe de chaque mesure, située en colonne 'o.label'.
+// En CSV la classe peut être un libellé textuel ; dans les autres formats elle est numérique.
+func readLabelled(path string, stdin io.Reader, o *options) (X [][]float64, labels []string, err error) {
+	format, err := o.streamFormat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if format != microClustering.CSVFormat {
+		data, err := readData(path, stdin, o)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, row := range data {
+			if o.label >= len(row) {
+				return nil, nil, fmt.Errorf("label column %d out of range", o.label)
+			}
+			labels = append(labels, strconv.FormatFloat(row[o.label], 'g', -1, 64))
+			X = append(X, append(append([]float64{}, row[:o.label]...), row[o.label+1:]...))
+		}
+		return X, labels, nil
+	}
+
+	r, err := openData(path, stdin)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer r.Close()
+	reader := csv.NewReader(r)
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return X, labels, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if row == 1 && o.header {
+			continue
+		}
+		if o.label >= len(record) {
+			return nil, nil, fmt.Errorf("label column %d out of range", o.label)
+		}
+		x := make([]float64, 0, len(record)-1)
+		for i, field := range record {
+			if i == o.label {
+				continue
+			}
+			v, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+			if err != nil {
+				return nil, nil, fmt.Errorf("row %d: column %d: invalid value %q", row, i+1, field)
+			}
+			x = append(x, v)
+		}
+		X = append(X, x)
+		labels = append(labels, strings.TrimSpace(record[o.label]))
+	}
+}
+
+// writeData écrit les mesures au format 'format'
+func writeData(w io.Writer, format microClustering.StreamFormat, data [][]float64) error {
+	switch format {
+	case microClustering.JSONLinesFormat:
+		encoder := json.NewEncoder(w)
+		for _, x := range data {
+			if err := encoder.Encode(x); err != nil {
+				return err
+			}
+		}
+		return nil
+	case microClustering.BinaryFormat:
+		return microClustering.WriteBinary(w, data)
+	}
+
+	writer := csv.NewWriter(w)
+	record := []string{}
+	for _, x := range data {
+		record = record[:0]
+		for _, v := range x {
+			record = append(record, strconv.FormatFloat(v, 'g', -1, 64))
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// sortedLabels renvoie les labels triés des classes d'un snapshot
+func sortedLabels(classes map[int]snapshotStats) (labels []int) {
+	for label := range classes {
+		labels = append(labels, label)
+	}
+	sort.Ints(labels)
+	return labels
+}
@@ -0,0 +1,48 @@
+// Commande sb4c : apprentissage, prédiction, génération et inspection de snapshots JSON de Clusterer ou de Classifier.
+//
+//	sb4c fit      [options] [données]            apprend les données et écrit le snapshot JSON
+//	sb4c predict  [options] snapshot [données]   écrit la classe prédite de chaque mesure
+//	sb4c score    [options] snapshot [données]   écrit le score d'outlier de chaque mesure (> 1 : outlier)
+//	sb4c generate [options] snapshot             écrit un jeu de données synthétique
+//	sb4c stats    snapshot                       résume les µC du snapshot
+//	sb4c merge    [options] snapshot...          fusionne les snapshots
+//
+// Les données sont lues sur l'entrée standard si le fichier est absent ou vaut "-". Leur format (csv, jsonl ou
+// binary) est déduit de l'extension du fichier ou précisé par -format. Sans -label, fit crée un Clusterer ;
+// avec -label, la colonne indiquée contient la classe de chaque mesure et fit crée un Classifier.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "sb4c:", err)
+		os.Exit(1)
+	}
+}
+
+// commands associe à chaque sous-commande sa fonction
+var commands = map[string]func(args []string, stdin io.Reader, stdout io.Writer) error{
+	"fit":      fit,
+	"predict":  predict,
+	"score":    score,
+	"generate": generate,
+	"stats":    stats,
+	"merge":    merge,
+}
+
+// run exécute la sous-commande args[0]
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: sb4c fit|predict|score|generate|stats|merge [options] [files]")
+	}
+	command, exists := commands[args[0]]
+	if !exists {
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	return command(args[1:], stdin, stdout)
+}
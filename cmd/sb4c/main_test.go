@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCommands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sb4c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	rnd := rand.New(rand.NewSource(1))
+	var train bytes.Buffer
+	train.WriteString("x,y,label\n")
+	for label, center := range [][]float64{{0, 0}, {10, 0}, {0, 10}} {
+		for i := 0; i < 100; i++ {
+			fmt.Fprintf(&train, "%f,%f,%d\n", center[0]+rnd.NormFloat64(), center[1]+rnd.NormFloat64(), label)
+		}
+	}
+	ioutil.WriteFile(path("train.csv"), train.Bytes(), 0644)
+	ioutil.WriteFile(path("test.jsonl"), []byte("[0,0]\n[10,0]\n[0,10]\n[40,40]\n"), 0644)
+
+	exec := func(args ...string) string {
+		var out bytes.Buffer
+		if err := run(args, strings.NewReader(""), &out); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+		return out.String()
+	}
+
+	exec("fit", "-header", "-label", "2", "-o", path("classifier.json"), path("train.csv"))
+	if got := exec("predict", path("classifier.json"), path("test.jsonl")); !strings.HasPrefix(got, "0\n1\n2\n") || strings.Count(got, "\n") != 4 {
+		t.Errorf("predicted labels %q", got)
+	}
+	scores := strings.Fields(exec("score", path("classifier.json"), path("test.jsonl")))
+	if len(scores) != 4 {
+		t.Fatalf("outlier scores %v", scores)
+	}
+	for i, expected := range []bool{false, false, false, true} {
+		if s, _ := strconv.ParseFloat(scores[i], 64); (s > 1) != expected {
+			t.Errorf("point %d: outlier score %v", i, s)
+		}
+	}
+
+	// libellés textuels en CSV
+	var named bytes.Buffer
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(&named, "%f,db,%f\n%f,api,%f\n", rnd.NormFloat64(), rnd.NormFloat64(), 10+rnd.NormFloat64(), rnd.NormFloat64())
+	}
+	ioutil.WriteFile(path("named.csv"), named.Bytes(), 0644)
+	ioutil.WriteFile(path("unnamed.csv"), []byte("0,db,0\n1,,1\n"), 0644)
+	exec("fit", "-label", "1", "-o", path("named.json"), path("named.csv"))
+	if got := exec("predict", path("named.json"), path("test.jsonl")); !strings.HasPrefix(got, "db\napi\n") {
+		t.Errorf("predicted text labels %q", got)
+	}
+
+	// Clusterer lu sur l'entrée standard
+	var out bytes.Buffer
+	unlabeled := strings.NewReader("0,0\n0.5,0\n10,10\n")
+	if err := run([]string{"fit", "-radius", "1", "-zones", "2"}, unlabeled, &out); err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(path("clusterer.json"), out.Bytes(), 0644)
+	exec("merge", "-o", path("merged.json"), path("clusterer.json"), path("clusterer.json"))
+	stats := exec("stats", path("merged.json"))
+	lines := strings.Split(strings.TrimSpace(stats), "\n")
+	if fields := strings.Fields(lines[len(lines)-1]); len(fields) != 8 || fields[1] != "2" || fields[3] != "6" {
+		t.Errorf("unexpected stats:\n%s", stats) // 2 µC, poids total 6
+	}
+
+	first := exec("generate", "-n", "20", "-seed", "3", path("classifier.json"))
+	if first != exec("generate", "-n", "20", "-seed", "3", path("classifier.json")) {
+		t.Error("generation with the same seed should be reproducible")
+	}
+	if rows := strings.Split(strings.TrimSpace(first), "\n"); len(rows) < 20 || strings.Count(rows[0], ",") != 2 {
+		t.Errorf("unexpected generated data:\n%s", first)
+	}
+
+	for _, args := range [][]string{
+		{"predict", path("clusterer.json"), path("test.jsonl")},
+		{"merge", path("clusterer.json"), path("classifier.json")},
+		{"fit", "-radius", "1", "-distance", "unknown", path("test.jsonl")},
+		{"fit", path("test.jsonl")},
+		{"fit", "-label", "1", path("unnamed.csv")},
+		{"fit", "-label", "3", path("named.csv")},
+		{"score", "-format", "csv", path("clusterer.json"), path("test.jsonl")},
+		{"unknown"},
+	} {
+		if err := run(args, strings.NewReader(""), ioutil.Discard); err == nil {
+			t.Errorf("%v should fail", args)
+		}
+	}
+}
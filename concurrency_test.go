@@ -0,0 +1,227 @@
+package microClustering
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+// à lancer avec go test -race
+func TestConcurrentClusterer(t *testing.T) {
+	c := NewClusterer(1, 1, 2, 3)
+	c.ReservoirSize = 5
+	c.MaxMicroClusters = 50
+	centers := [][]float64{{0, 0}, {5, 5}, {10, 0}}
+
+	var wg sync.WaitGroup
+	const writers, readers, batches = 4, 4, 50
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := 0; b < batches; b++ {
+				X, _ := blobs(10, centers, 1)
+				c.Add(X)
+				if b%10 == 9 {
+					c.RandomDelete(0.05, 1)
+				}
+			}
+		}()
+	}
+	for r := 0; r < readers; r++ {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+			for i := 0; i < batches; i++ {
+				x := []float64{rand.Float64() * 10, rand.Float64() * 10}
+				switch i % 6 {
+				case 0:
+					c.IsOutlier(x)
+				case 1:
+					c.KNN(x, 3)
+				case 2:
+					if c.CountMC() > 0 {
+						c.Generate(20)
+					}
+				case 3:
+					if _, err := c.ToJson(); err != nil {
+						t.Error(err)
+					}
+				case 4:
+					c.MacroExemplars()
+				case 5:
+					c.Exemplars(0)
+				}
+			}
+		}(r)
+	}
+	wg.Wait()
+
+	if n := c.CountMC(); n == 0 || n > 50 {
+		t.Errorf("%d µC after concurrent ingestion", n)
+	}
+	if c.tick != writers*batches*10*int64(len(centers)) {
+		t.Errorf("%d points processed, expected %d", c.tick, writers*batches*10*len(centers))
+	}
+}
+
+// à lancer avec go test -race : les modèles construits sur un Clusterer prennent son verrou
+func TestConcurrentModels(t *testing.T) {
+	centers := [][]float64{{0, 0}, {5, 5}}
+	X, Y := blobs(20, centers, 0.5)
+	targets := make([]float64, len(Y))
+	labelSets := make([][]int, len(Y))
+	for i, y := range Y {
+		targets[i] = float64(y)
+		labelSets[i] = []int{y}
+	}
+
+	r := NewRegressor(1, 1, 1, 3)
+	m := NewMultiLabel(1, 1, 1, 3)
+	s := NewSemiSupervised(1, 1, 1, 3)
+	c := NewClassifier(0, 1, 1, 2, 3)
+	for _, err := range []error{r.FitXY(X, targets), m.FitXY(X, labelSets), s.AddLabelled(X, Y), c.FitXY(X, Y)} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	const batches = 30
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for b := 0; b < batches; b++ {
+			X, Y := blobs(5, centers, 0.5)
+			r.FitXY(X, targets[:len(X)])
+			m.FitXY(X, labelSets[:len(X)])
+			s.AddLabelled(X, Y)
+			s.Add(X)
+			c.classes[0].Add(X) // apprentissage direct d'une classe pendant les prédictions
+			c.classes[1].RandomDelete(0.05, 1)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < batches; i++ {
+			x := [][]float64{{rand.Float64() * 5, rand.Float64() * 5}}
+			r.Predict(x, 3)
+			m.Predict(x, 3)
+			s.Predict(x, 3)
+			for _, decision := range []DecisionRule{KNNDecision, CentroidDecision, ParzenDecision} {
+				c.Decision = decision
+				c.KNN(x, 3)
+			}
+			c.Uncertainty(x[0], 3, SharedMCQuery)
+			for _, model := range []interface{ ToJson() ([]byte, error) }{r, m, s, c} {
+				if _, err := model.ToJson(); err != nil {
+					t.Error(err)
+				}
+			}
+		}
+	}()
+	wg.Wait()
+}
+
+// benchmarkContention mesure le débit d'apprentissage lorsque 'readers' goroutines interrogent le Clusterer en continu
+func benchmarkContention(b *testing.B, readers int, query func(c *Clusterer, x []float64)) {
+	c := NewClusterer(0.5, 1, 1, 3)
+	centers := [][]float64{{0, 0}, {5, 5}, {10, 0}, {0, 10}}
+	X, _ := blobs(250, centers, 2)
+	c.Add(X)
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for r := 0; r < readers; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			x := []float64{0, 0}
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					x[0], x[1] = rand.Float64()*10, rand.Float64()*10
+					query(c, x)
+				}
+			}
+		}()
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Add(X[i%len(X) : i%len(X)+1])
+	}
+	b.StopTimer()
+	close(stop)
+	wg.Wait()
+}
+
+func BenchmarkAddNoReader(b *testing.B) {
+	benchmarkContention(b, 0, nil)
+}
+
+func BenchmarkAddWithKNNReaders(b *testing.B) {
+	benchmarkContention(b, 4, func(c *Clusterer, x []float64) { c.KNN(x, 3) })
+}
+
+func BenchmarkAddWithOutlierReaders(b *testing.B) {
+	benchmarkContention(b, 4, func(c *Clusterer, x []float64) { c.IsOutlier(x) })
+}
+
+func BenchmarkAddWithGenerateReaders(b *testing.B) {
+	benchmarkContention(b, 4, func(c *Clusterer, x []float64) { c.Generate(100) })
+}
+
+func BenchmarkParallelQueries(b *testing.B) {
+	c := NewClusterer(0.5, 1, 1, 3)
+	X, _ := blobs(250, [][]float64{{0, 0}, {5, 5}, {10, 0}, {0, 10}}, 2)
+	c.Add(X)
+	b.RunParallel(func(pb *testing.PB) {
+		x := []float64{0, 0}
+		for pb.Next() {
+			x[0], x[1] = rand.Float64()*10, rand.Float64()*10
+			c.KNN(x, 3)
+		}
+	})
+}
+
+// à lancer avec go test -race : l'export lit le compteur de rejets pendant des prédictions concurrentes
+func TestConcurrentClassifierExport(t *testing.T) {
+	X, Y := blobs(20, [][]float64{{0, 0}, {5, 5}}, 0.5)
+	c := NewClassifier(0, 1, 1, 1, 3)
+	c.CheckOutliers = true
+	if err := c.FitXY(X, Y); err != nil {
+		t.Fatal(err)
+	}
+	h := NewHierarchical(NewLabelTree(), 1, 1, 1, 3)
+	if err := h.FitXY(X, Y); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				if r%2 == 0 {
+					c.KNN([][]float64{{50, 50}}, 1)
+					h.Predict([][]float64{{50, 50}}, 1)
+				} else {
+					if _, err := c.ToJson(); err != nil {
+						t.Error(err)
+					}
+					if _, err := h.ToJson(); err != nil {
+						t.Error(err)
+					}
+				}
+			}
+		}(r)
+	}
+	wg.Wait()
+	if c.Rejected() != 40 {
+		t.Errorf("rejected=%d after concurrent predictions", c.Rejected())
+	}
+}
@@ -0,0 +1,164 @@
+package microClustering
+
+import (
+	"math"
+)
+
+/*
+  Règles de décision du classifieur
+
+  - KNNDecision : vote des k µC les plus proches, la distance étant divisée par le poids des µC (règle d'origine)
+  - PlainKNNDecision : vote des k µC les plus proches sans division par le poids
+  - CentroidDecision : classe du centroïde le plus proche, le centroïde d'une classe étant la moyenne des centres
+    de ses µC pondérée par leur poids
+  - ParzenDecision : estimation de densité par fenêtre de Parzen ; chaque zone d'un µC est un noyau gaussien centré
+    sur le µC, de largeur le rayon extérieur de la zone et de poids le nombre de mesures de la zone
+*/
+
+// DecisionRule définit la règle de décision utilisée pour la prédiction
+type DecisionRule int
+
+const (
+	// KNNDecision : vote des k µC les plus proches, distance divisée par le poids des µC
+	KNNDecision DecisionRule = iota
+	// PlainKNNDecision : vote des k µC les plus proches, sans prise en compte du poids dans la distance
+	PlainKNNDecision
+	// CentroidDecision : centroïde pondéré le plus proche
+	CentroidDecision
+	// ParzenDecision : densité estimée par fenêtre de Parzen sur les zones des µC
+	ParzenDecision
+)
+
+// centroidProba renvoie la probabilité de chaque classe pour 'x', proportionnelle à l'inverse de la distance
+// au centroïde pondéré de la classe
+func (c *Classifier) centroidProba(x []float64) map[int]float64 {
+	proba := make(map[int]float64)
+	total := 0.0
+	for label, cl := range c.classes {
+		proba[label] = 0
+		cl.mu.RLock()
+		centroid, d := cl.centroid(), 0.0
+		if centroid != nil {
+			d = cl.distance(x, centroid)
+		}
+		cl.mu.RUnlock()
+		if centroid == nil {
+			continue
+		}
+		w := 1 / math.Max(d, 1e-12)
+		proba[label] = w
+		total += w
+	}
+	if total > 0 {
+		for label := range proba {
+			proba[label] /= total
+		}
+	}
+	return proba
+}
+
+// centroid renvoie la moyenne des centres des µC pondérée par leur poids, nil si le Clusterer est vide, le verrou
+// étant déjà pris
+func (c *Clusterer) centroid() []float64 {
+	var centroid []float64
+	total := 0.0
+	for _, mc := range c.mc {
+		if centroid == nil {
+			centroid = make([]float64, len(mc.Center))
+		}
+		for i := range mc.Center {
+			centroid[i] += float64(mc.Weight) * mc.Center[i]
+		}
+		total += float64(mc.Weight)
+	}
+	if total == 0 {
+		return nil
+	}
+	for i := range centroid {
+		centroid[i] /= total
+	}
+	return centroid
+}
+
+// parzenProba renvoie la probabilité de chaque classe pour 'x', proportionnelle à la densité estimée des mesures
+// de la classe au point 'x'
+func (c *Classifier) parzenProba(x []float64) map[int]float64 {
+	logDensity := make(map[int]float64)
+	max := math.Inf(-1)
+	for label, cl := range c.classes {
+		cl.mu.RLock()
+		logDensity[label] = cl.logDensity(x)
+		cl.mu.RUnlock()
+		max = math.Max(max, logDensity[label])
+	}
+
+	// softmax des log-densités
+	proba := make(map[int]float64)
+	total := 0.0
+	for label, l := range logDensity {
+		p := 0.0
+		if !math.IsInf(max, -1) {
+			p = math.Exp(l - max)
+		}
+		proba[label] = p
+		total += p
+	}
+	if total > 0 {
+		for label := range proba {
+			proba[label] /= total
+		}
+	}
+	return proba
+}
+
+// logDensity renvoie le logarithme de la densité (non normalisée par le nombre de mesures) des µC au point 'x'.
+// Chaque zone est un noyau gaussien de largeur son rayon extérieur, pondéré par le nombre de mesures de la zone.
+// Le verrou est déjà pris.
+func (c *Clusterer) logDensity(x []float64) float64 {
+	dim := float64(len(x))
+	terms := []float64{}
+	for _, mc := range c.mc {
+		d := c.distance(x, mc.Center)
+		radius := c.radiusOf(mc)
+
+		zones := 0
+		for _, n := range mc.Zones {
+			zones += n
+		}
+		if zones == 0 { // pas de répartition par zone : un seul noyau de la largeur du µC
+			terms = append(terms, kernel(d, radius, dim, float64(mc.Weight)))
+			continue
+		}
+		for z, n := range mc.Zones {
+			if n == 0 {
+				continue
+			}
+			h := float64(z+1) * radius / float64(len(mc.Zones))
+			w := float64(mc.Weight) * float64(n) / float64(zones) // le poids du µC est réparti entre les zones
+			terms = append(terms, kernel(d, h, dim, w))
+		}
+	}
+	return logSumExp(terms)
+}
+
+// kernel renvoie le logarithme du noyau gaussien de largeur 'h' à la distance 'd', pondéré par 'w'
+func kernel(d, h, dim, w float64) float64 {
+	h = math.Max(h, 1e-12)
+	return math.Log(w) - d*d/(2*h*h) - dim*math.Log(h)
+}
+
+// logSumExp renvoie log(somme(exp(v))) sans dépassement de capacité
+func logSumExp(values []float64) float64 {
+	max := math.Inf(-1)
+	for _, v := range values {
+		max = math.Max(max, v)
+	}
+	if math.IsInf(max, -1) {
+		return max
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += math.Exp(v - max)
+	}
+	return max + math.Log(sum)
+}
@@ -0,0 +1,78 @@
+package microClustering
+
+import (
+	"fmt"
+	"testing"
+)
+
+var decisionRules = []struct {
+	name string
+	rule DecisionRule
+}{
+	{"knn", KNNDecision},
+	{"plain-knn", PlainKNNDecision},
+	{"centroid", CentroidDecision},
+	{"parzen", ParzenDecision},
+}
+
+func TestDecisionRules(t *testing.T) {
+	centers := [][]float64{{0, 0}, {6, 0}, {0, 6}}
+	X, Y := blobs(100, centers, 0.7)
+	testX, testY := blobs(30, centers, 0.7)
+
+	for _, d := range decisionRules {
+		c := NewClassifier(0, 1, 1, 3, 3)
+		c.Decision = d.rule
+		if err := c.FitXY(X, Y); err != nil {
+			t.Fatal(err)
+		}
+		acc := Accuracy(testY, c.KNN(testX, 3))
+		fmt.Println(d.name, "accuracy=", acc)
+		if acc < 0.9 {
+			t.Errorf("%s: accuracy %g, expected at least 0.9", d.name, acc)
+		}
+
+		proba := c.PredictProba([]float64{6, 0}, 3)
+		if len(proba) != len(centers) {
+			t.Errorf("%s: %d probabilities, expected %d", d.name, len(proba), len(centers))
+		}
+		if proba[1] < proba[0] || proba[1] < proba[2] {
+			t.Errorf("%s: class 1 should be the most probable at its center, got %v", d.name, proba)
+		}
+
+		// la règle de décision est conservée à l'export
+		data, err := c.ToJson()
+		if err != nil {
+			t.Fatal(err)
+		}
+		imported, err := NewClassifierFromJson(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if imported.Decision != d.rule {
+			t.Errorf("%s: decision rule %d after import", d.name, imported.Decision)
+		}
+	}
+}
+
+func BenchmarkDecision(b *testing.B) {
+	centers := [][]float64{{0, 0}, {3, 0}, {0, 3}, {3, 3}}
+	X, Y := blobs(500, centers, 1)
+	testX, testY := blobs(50, centers, 1)
+
+	for _, d := range decisionRules {
+		b.Run(d.name, func(b *testing.B) {
+			c := NewClassifier(0, 0.5, 1, 3, 3)
+			c.Decision = d.rule
+			if err := c.FitXY(X, Y); err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			var pred []int
+			for i := 0; i < b.N; i++ {
+				pred = c.KNN(testX, 5)
+			}
+			b.ReportMetric(Accuracy(testY, pred), "accuracy")
+		})
+	}
+}
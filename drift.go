@@ -0,0 +1,395 @@
+package microClustering
+
+import (
+	"math"
+)
+
+/*
+  Détection de dérive (concept drift)
+
+  - un DriftDetector surveille une suite de valeurs et signale un changement de leur moyenne :
+      - ADWIN (Bifet et Gavaldà) : fenêtre adaptative coupée lorsque les moyennes de deux sous-fenêtres diffèrent
+      - Page-Hinkley : cumul des écarts à la moyenne, dans les deux sens
+      - DDM (Gama et al.) : taux d'erreur d'un classifieur (valeurs 0 ou 1)
+  - un DriftMonitor attaché à un Clusterer observe, pour chaque mesure apprise :
+      - la création d'un nouveau µC (1 si la mesure crée un µC, 0 sinon)
+      - le taux d'outliers (1 si la mesure crée un µC ou est absorbée par un µC non représentatif)
+      - le poids relatif du µC qui absorbe la mesure (poids / poids moyen des µC, 0 pour un nouveau µC)
+  - le DriftMonitor du classifieur (Classifier.Drift) observe l'erreur de prédiction du flux (ObservePrediction)
+  - chaque dérive est signalée par OnDrift et peut déclencher l'oubli ou la remise à zéro des µC
+  - les détecteurs ne sont pas exportés en JSON
+*/
+
+// DriftDetector détecte un changement de la moyenne d'une suite de valeurs
+type DriftDetector interface {
+	Add(v float64) bool // ajoute une valeur et renvoie true si une dérive est détectée
+	Estimate() float64  // moyenne courante des valeurs
+	Reset()             // oublie toutes les valeurs
+}
+
+// ADWIN conserve une fenêtre de valeurs dont il supprime les plus anciennes lorsque la moyenne a changé
+type ADWIN struct {
+	Delta     float64 // confiance de la détection (0.002 par défaut)
+	MaxWindow int     // taille maximum de la fenêtre (1000 par défaut)
+	Clock     int     // la fenêtre est testée toutes les 'Clock' valeurs (32 par défaut)
+	window    []float64
+	count     int
+}
+
+// NewADWIN crée un détecteur ADWIN de confiance 'delta'
+func NewADWIN(delta float64) *ADWIN {
+	return &ADWIN{Delta: delta}
+}
+
+// Add ajoute une valeur à la fenêtre et la coupe si deux sous-fenêtres ont des moyennes significativement différentes
+func (a *ADWIN) Add(v float64) bool {
+	maxWindow := a.MaxWindow
+	if maxWindow <= 0 {
+		maxWindow = 1000
+	}
+	clock := a.Clock
+	if clock <= 0 {
+		clock = 32
+	}
+	a.window = append(a.window, v)
+	if len(a.window) > maxWindow {
+		a.window = a.window[1:]
+	}
+	a.count++
+	if a.count%clock != 0 {
+		return false
+	}
+
+	drift := false
+	for a.cut() {
+		drift = true
+	}
+	return drift
+}
+
+// cut supprime les valeurs antérieures à la première coupure significative de la fenêtre
+func (a *ADWIN) cut() bool {
+	delta := a.Delta
+	if delta <= 0 {
+		delta = 0.002
+	}
+	const minSide = 5
+	n := len(a.window)
+	if n < 2*minSide {
+		return false
+	}
+
+	total, squares := 0.0, 0.0
+	for _, v := range a.window {
+		total += v
+		squares += v * v
+	}
+	mean := total / float64(n)
+	variance := math.Max(squares/float64(n)-mean*mean, 0)
+	logTerm := math.Log(2 * math.Log(float64(n)) / delta)
+
+	sum0 := 0.0
+	for i := 0; i < n-minSide; i++ {
+		sum0 += a.window[i]
+		n0 := float64(i + 1)
+		if i+1 < minSide {
+			continue
+		}
+		n1 := float64(n) - n0
+		m := 1 / (1/n0 + 1/n1)
+		eps := math.Sqrt(2/m*variance*logTerm) + 2/(3*m)*logTerm
+		if math.Abs(sum0/n0-(total-sum0)/n1) > eps {
+			a.window = a.window[i+1:]
+			return true
+		}
+	}
+	return false
+}
+
+// Estimate renvoie la moyenne des valeurs de la fenêtre
+func (a *ADWIN) Estimate() float64 {
+	if len(a.window) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, v := range a.window {
+		total += v
+	}
+	return total / float64(len(a.window))
+}
+
+// Reset vide la fenêtre
+func (a *ADWIN) Reset() {
+	a.window = nil
+	a.count = 0
+}
+
+// PageHinkley cumule les écarts à la moyenne et signale une dérive lorsque le cumul dépasse le seuil Lambda
+type PageHinkley struct {
+	Delta     float64 // amplitude des variations tolérées
+	Lambda    float64 // seuil de détection
+	Alpha     float64 // facteur d'oubli du cumul (1 : pas d'oubli)
+	MinValues int     // nombre de valeurs avant la première détection (30 par défaut)
+	n         int
+	mean      float64
+	up, down  float64 // cumuls des écarts à la hausse et à la baisse
+	minUp     float64
+	maxDown   float64
+}
+
+// NewPageHinkley crée un détecteur Page-Hinkley tolérant des variations 'delta' et de seuil 'lambda'
+func NewPageHinkley(delta, lambda float64) *PageHinkley {
+	return &PageHinkley{Delta: delta, Lambda: lambda, Alpha: 1}
+}
+
+// Add ajoute une valeur et renvoie true si la moyenne a augmenté ou diminué de façon significative
+func (p *PageHinkley) Add(v float64) bool {
+	alpha := p.Alpha
+	if alpha <= 0 {
+		alpha = 1
+	}
+	minValues := p.MinValues
+	if minValues <= 0 {
+		minValues = 30
+	}
+	p.n++
+	p.mean += (v - p.mean) / float64(p.n)
+	p.up = alpha*p.up + v - p.mean - p.Delta
+	p.down = alpha*p.down + v - p.mean + p.Delta
+	p.minUp = math.Min(p.minUp, p.up)
+	p.maxDown = math.Max(p.maxDown, p.down)
+	if p.n < minValues {
+		return false
+	}
+	if p.up-p.minUp > p.Lambda || p.maxDown-p.down > p.Lambda {
+		p.Reset()
+		return true
+	}
+	return false
+}
+
+// Estimate renvoie la moyenne des valeurs depuis la dernière dérive
+func (p *PageHinkley) Estimate() float64 {
+	return p.mean
+}
+
+// Reset oublie toutes les valeurs
+func (p *PageHinkley) Reset() {
+	p.n = 0
+	p.mean = 0
+	p.up, p.down = 0, 0
+	p.minUp, p.maxDown = 0, 0
+}
+
+// DDM surveille un taux d'erreur : chaque valeur vaut 1 pour une erreur, 0 pour une prédiction correcte
+type DDM struct {
+	MinValues int     // nombre de valeurs avant la première détection (30 par défaut)
+	Warning   float64 // nombre d'écarts-types déclenchant l'alerte (2 par défaut)
+	Drift     float64 // nombre d'écarts-types déclenchant la dérive (3 par défaut)
+	n         int
+	p         float64
+	pMin      float64 // taux d'erreur minimum observé
+	sMin      float64 // écart-type associé (0 : pas encore de référence)
+	warning   bool
+}
+
+// NewDDM crée un détecteur DDM avec les seuils par défaut
+func NewDDM() *DDM {
+	return &DDM{}
+}
+
+// Add ajoute une valeur et renvoie true si le taux d'erreur a augmenté de façon significative
+func (d *DDM) Add(v float64) bool {
+	minValues := d.MinValues
+	if minValues <= 0 {
+		minValues = 30
+	}
+	warning, drift := d.Warning, d.Drift
+	if warning <= 0 {
+		warning = 2
+	}
+	if drift <= 0 {
+		drift = 3
+	}
+
+	d.n++
+	d.p += (v - d.p) / float64(d.n)
+	s := math.Sqrt(d.p * (1 - d.p) / float64(d.n))
+	if d.n < minValues {
+		return false
+	}
+	if s > 0 && (d.sMin == 0 || d.p+s < d.pMin+d.sMin) { // un écart-type nul (aucune erreur) ne sert pas de référence
+		d.pMin, d.sMin = d.p, s
+	}
+	if d.sMin == 0 {
+		return false
+	}
+	d.warning = d.p+s > d.pMin+warning*d.sMin
+	if d.p+s > d.pMin+drift*d.sMin {
+		d.Reset()
+		return true
+	}
+	return false
+}
+
+// InWarning renvoie true si le taux d'erreur a dépassé le seuil d'alerte
+func (d *DDM) InWarning() bool {
+	return d.warning
+}
+
+// Estimate renvoie le taux d'erreur depuis la dernière dérive
+func (d *DDM) Estimate() float64 {
+	return d.p
+}
+
+// Reset oublie toutes les valeurs
+func (d *DDM) Reset() {
+	d.n = 0
+	d.p = 0
+	d.pMin, d.sMin = 0, 0
+	d.warning = false
+}
+
+// DriftSignal identifie la grandeur surveillée ayant dérivé
+type DriftSignal int
+
+const (
+	// NewMCSignal : taux de création de nouveaux µC
+	NewMCSignal DriftSignal = iota
+	// OutlierSignal : taux de mesures tombant hors des µC représentatifs
+	OutlierSignal
+	// WeightSignal : poids relatif des µC qui absorbent les mesures
+	WeightSignal
+	// ErrorSignal : taux d'erreur de prédiction
+	ErrorSignal
+)
+
+// DriftAction définit l'action déclenchée par une dérive
+type DriftAction int
+
+const (
+	// NoDriftAction : la dérive est seulement signalée
+	NoDriftAction DriftAction = iota
+	// ForgetOnDrift : applique l'oubli (RandomDelete) paramétré par Forgetting
+	ForgetOnDrift
+	// ResetOnDrift : supprime tous les µC
+	ResetOnDrift
+)
+
+// DriftEvent décrit une dérive détectée
+type DriftEvent struct {
+	Signal   DriftSignal
+	Class    int     // classe surveillée (-1 : flux complet ou Clusterer seul)
+	Tick     int64   // nombre de mesures traitées par le Clusterer (ou de prédictions observées pour ErrorSignal)
+	Estimate float64 // moyenne de la grandeur surveillée après la dérive
+}
+
+// DriftMonitor regroupe les détecteurs surveillant un Clusterer ou le flux d'un classifieur.
+// Un détecteur nil n'est pas utilisé.
+type DriftMonitor struct {
+	NewMC   DriftDetector // création de nouveaux µC
+	Outlier DriftDetector // mesures hors des µC représentatifs
+	Weight  DriftDetector // poids relatif du µC absorbant la mesure
+	Error   DriftDetector // erreur de prédiction (classifieur uniquement)
+
+	Action     DriftAction      // action déclenchée par une dérive
+	Forgetting Forgetting       // oubli appliqué par ForgetOnDrift (Every n'est pas utilisé)
+	OnDrift    func(DriftEvent) // appelée pour chaque dérive détectée
+
+	class int   // classe surveillée
+	ticks int64 // nombre de prédictions observées
+}
+
+// NewDriftMonitor crée un DriftMonitor sans détecteur
+func NewDriftMonitor() *DriftMonitor {
+	return &DriftMonitor{class: -1}
+}
+
+// observe ajoute la valeur au détecteur et renvoie true si une dérive est signalée
+func (m *DriftMonitor) observe(detector DriftDetector, signal DriftSignal, v float64, tick int64) bool {
+	if detector == nil || !detector.Add(v) {
+		return false
+	}
+	if m.OnDrift != nil {
+		m.OnDrift(DriftEvent{Signal: signal, Class: m.class, Tick: tick, Estimate: detector.Estimate()})
+	}
+	return true
+}
+
+// observeDrift transmet au DriftMonitor du Clusterer une mesure qui vient d'être apprise.
+// 'created' indique si la mesure a créé un µC, sinon 'weight' est le poids du µC absorbant avant l'ajout.
+func (c *Clusterer) observeDrift(created bool, weight int) {
+	m := c.Drift
+	newMC, outlier, relative := 0.0, 0.0, 0.0
+	if created {
+		newMC, outlier = 1, 1
+	} else {
+		if float64(weight) < c.mediumSize-c.outlierThreshold*c.sigmaSize {
+			outlier = 1
+		}
+		if c.mediumSize > 0 {
+			relative = float64(weight) / c.mediumSize
+		}
+	}
+
+	drift := m.observe(m.NewMC, NewMCSignal, newMC, c.tick)
+	drift = m.observe(m.Outlier, OutlierSignal, outlier, c.tick) || drift
+	drift = m.observe(m.Weight, WeightSignal, relative, c.tick) || drift
+	if drift {
+		c.applyDrift(m)
+	}
+}
+
+// applyDrift applique au Clusterer l'action déclenchée par une dérive du DriftMonitor 'm', le verrou étant déjà pris
+func (c *Clusterer) applyDrift(m *DriftMonitor) {
+	switch m.Action {
+	case ForgetOnDrift:
+		if m.Forgetting.Pct > 0 {
+			proba := m.Forgetting.Proba
+			if proba <= 0 {
+				proba = 1
+			}
+			c.randomDelete(m.Forgetting.Pct, proba)
+		}
+	case ResetOnDrift:
+		c.mc = nil
+		c.updateStats()
+		if c.Drift != nil {
+			c.Drift.reset()
+		}
+	}
+}
+
+// reset oublie les valeurs de tous les détecteurs
+func (m *DriftMonitor) reset() {
+	for _, detector := range []DriftDetector{m.NewMC, m.Outlier, m.Weight, m.Error} {
+		if detector != nil {
+			detector.Reset()
+		}
+	}
+}
+
+// ObservePrediction transmet au DriftMonitor du flux (Drift) le résultat d'une prédiction dont le label est connu.
+// Une dérive de l'erreur applique l'action du DriftMonitor à toutes les classes.
+func (c *Classifier) ObservePrediction(yTrue, yPred int) {
+	m := c.Drift
+	if m == nil {
+		return
+	}
+	m.ticks++
+	e := 0.0
+	if yTrue != yPred {
+		e = 1
+	}
+	if m.observe(m.Error, ErrorSignal, e, m.ticks) {
+		for _, label := range c.labels() {
+			cl := c.classes[label]
+			cl.mu.Lock()
+			cl.applyDrift(m)
+			cl.mu.Unlock()
+		}
+		if m.Action == ResetOnDrift {
+			m.reset()
+		}
+	}
+}
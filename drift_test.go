@@ -0,0 +1,97 @@
+package microClustering
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestDriftDetectors(t *testing.T) {
+	detectors := map[string]DriftDetector{
+		"adwin":        NewADWIN(0.002),
+		"page-hinkley": NewPageHinkley(0.005, 50),
+		"ddm":          NewDDM(),
+	}
+	for name, d := range detectors {
+		// taux d'erreur de 10% puis de 60%, flux reproductible
+		r := rand.New(rand.NewSource(1))
+		detected := -1
+		for i := 0; i < 2000; i++ {
+			p := 0.1
+			if i >= 1000 {
+				p = 0.6
+			}
+			v := 0.0
+			if r.Float64() < p {
+				v = 1
+			}
+			if d.Add(v) && detected < 0 {
+				detected = i
+			}
+		}
+		fmt.Println(name, "drift detected at", detected)
+		if detected < 1000 || detected > 1300 {
+			t.Errorf("%s: drift detected at %d, expected shortly after 1000", name, detected)
+		}
+	}
+}
+
+func TestClustererDrift(t *testing.T) {
+	c := NewClusterer(1, 1, 1, 3)
+	events := []DriftEvent{}
+	c.Drift = NewDriftMonitor()
+	c.Drift.NewMC = NewADWIN(0.002)
+	c.Drift.Action = ResetOnDrift
+	c.Drift.OnDrift = func(e DriftEvent) { events = append(events, e) }
+
+	// flux stable puis déplacement des données
+	X, _ := blobs(1000, [][]float64{{0, 0}}, 0.5)
+	c.Add(X)
+	if len(events) != 0 {
+		t.Errorf("no drift expected on a stable stream, got %v", events)
+	}
+	X, _ = blobs(300, [][]float64{{20, 20}}, 5)
+	c.Add(X)
+	if len(events) == 0 {
+		t.Fatal("drift expected after the stream moved")
+	}
+	if events[0].Signal != NewMCSignal || events[0].Class != -1 {
+		t.Errorf("unexpected event %+v", events[0])
+	}
+	for _, mc := range c.mc {
+		if EuclidianDistance(mc.Center, []float64{0, 0}) < 2 {
+			t.Errorf("µC %v should have been removed by the reset", mc.Center)
+		}
+	}
+}
+
+func TestClassifierDrift(t *testing.T) {
+	c := NewClassifier(0, 1, 1, 1, 3)
+	classEvents := map[int]int{}
+	c.ClassDrift = func(label int) *DriftMonitor {
+		m := NewDriftMonitor()
+		m.NewMC = NewADWIN(0.002)
+		m.OnDrift = func(e DriftEvent) { classEvents[e.Class]++ }
+		return m
+	}
+	streamEvents := 0
+	c.Drift = NewDriftMonitor()
+	c.Drift.Error = NewDDM()
+	c.Drift.Action = ForgetOnDrift
+	c.Drift.Forgetting = Forgetting{Pct: 0.5}
+	c.Drift.OnDrift = func(e DriftEvent) { streamEvents++ }
+
+	// les classes échangent leurs positions : l'erreur augmente
+	X, Y := blobs(500, [][]float64{{0, 0}, {5, 5}}, 0.5)
+	X2, Y2 := blobs(500, [][]float64{{5, 5}, {0, 0}}, 0.5)
+	if _, err := Prequential(c, append(X, X2...), append(Y, Y2...), 3); err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println("stream drifts:", streamEvents, "class drifts:", classEvents)
+	if streamEvents == 0 {
+		t.Error("prediction error drift expected")
+	}
+	if classEvents[0] != 0 || classEvents[1] != 0 {
+		t.Errorf("no class drift expected, the classes only moved to known places: %v", classEvents)
+	}
+}
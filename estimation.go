@@ -0,0 +1,194 @@
+package microClustering
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// RadiusEstimation définit la stratégie d'estimation automatique du rayon des µC
+type RadiusEstimation int
+
+const (
+	// EstimateMeanNN : distance au plus proche voisin augmentée de deux écarts-types (estimation historique)
+	EstimateMeanNN RadiusEstimation = iota
+	// EstimateQuantileNN : quantile RadiusQuantile des distances au plus proche voisin
+	EstimateQuantileNN
+	// EstimateTargetMC : rayon pour lequel l'apprentissage de la classe produit environ TargetMC µC
+	EstimateTargetMC
+)
+
+// nnDistances calcule la distance au plus proche voisin d'un échantillon de 100 points
+func nnDistances(classData [][]float64, distance DistanceFunc) (distances []float64) {
+	for nb := 0; nb < 100; nb++ {
+		i := rand.Intn(len(classData))
+		min := math.MaxFloat64
+		for j := range classData {
+			if i != j {
+				min = math.Min(min, distance(classData[i], classData[j]))
+			}
+		}
+		distances = append(distances, min)
+	}
+	return distances
+}
+
+// radiusFor renvoie le rayon à utiliser pour la classe 'label'
+func (c *Classifier) radiusFor(label int) float64 {
+	if r, exists := c.ClassRadius[label]; exists && r > 0 {
+		return r
+	}
+	return c.Radius
+}
+
+// radiusKnown indique si le rayon de la classe 'label' est connu
+func (c *Classifier) radiusKnown(label int) bool {
+	if _, exists := c.classes[label]; exists {
+		return true
+	}
+	if c.PerClassRadius {
+		return c.ClassRadius[label] > 0
+	}
+	return c.radiusFor(label) > 0
+}
+
+// estimateRadii estime les rayons inconnus à partir des données regroupées par label :
+// le rayon de chaque nouvelle classe si PerClassRadius est activé, sinon le rayon global s'il n'est pas fixé.
+// Une classe dont le rayon ne peut pas être estimé (moins de 2 mesures, mesures confondues) reçoit le rayon global,
+// ou à défaut la moyenne des rayons estimés. Une erreur est renvoyée si aucun rayon n'est disponible.
+func (c *Classifier) estimateRadii(groups map[int][][]float64) error {
+	labels := []int{}
+	for label, data := range groups {
+		if len(data) >= 2 { // il faut au moins un plus proche voisin
+			labels = append(labels, label)
+		}
+	}
+	sort.Ints(labels)
+
+	if c.PerClassRadius {
+		if c.ClassRadius == nil {
+			c.ClassRadius = make(map[int]float64)
+		}
+		sum, n := 0.0, 0
+		for _, label := range labels {
+			if c.radiusKnown(label) {
+				continue
+			}
+			if r := c.estimateRadius(groups[label]); r > 0 {
+				c.ClassRadius[label] = r
+				sum += r
+				n++
+				if c.Verbose > 0 {
+					fmt.Println("=> class ", label, " radius=", r)
+				}
+			}
+		}
+
+		// classes dont le rayon n'a pas pu être estimé
+		fallback := c.Radius
+		if fallback <= 0 && n > 0 {
+			fallback = sum / float64(n)
+		}
+		if fallback <= 0 {
+			for _, r := range c.ClassRadius {
+				sum += r
+				n++
+			}
+			if n > 0 {
+				fallback = sum / float64(n)
+			}
+		}
+		for label := range groups {
+			if c.radiusKnown(label) {
+				continue
+			}
+			if fallback <= 0 {
+				return fmt.Errorf("cannot estimate the radius of class %d from %d points", label, len(groups[label]))
+			}
+			c.ClassRadius[label] = fallback
+		}
+		return nil
+	}
+
+	if c.Radius != 0 {
+		return nil
+	}
+	if len(labels) == 0 {
+		return fmt.Errorf("cannot estimate the radius: every class has less than 2 points")
+	}
+
+	radius := 0.0
+	if c.RadiusEstimation == EstimateMeanNN {
+		var (
+			stdDev  []float64
+			moyenne []float64
+		)
+		// calcule les distances moyennes par classe
+		for _, label := range labels {
+			// calcule des statistiques de distance entre points pour estimer automatiquement la bonne taille de cluster
+			mean, std := nnStats(groups[label], c.distanceFunc())
+			moyenne = append(moyenne, mean)
+			stdDev = append(stdDev, std)
+		}
+		// calcule le rayon en fonction des distances moyennes de chaque classe
+		radius = radiusFromStats(moyenne, stdDev)
+	} else {
+		// moyenne des rayons estimés pour chaque classe
+		sum := 0.0
+		for _, label := range labels {
+			sum += c.estimateRadius(groups[label])
+		}
+		radius = sum / float64(len(labels))
+	}
+	if radius <= 0 || math.IsNaN(radius) {
+		return fmt.Errorf("cannot estimate the radius: points of each class are identical")
+	}
+	c.Radius = radius
+	if c.Verbose > 0 {
+		fmt.Println("=> radius=", c.Radius, " seuil=", c.threshold)
+	}
+	return nil
+}
+
+// estimateRadius estime le rayon des µC d'une classe selon la stratégie RadiusEstimation
+func (c *Classifier) estimateRadius(data [][]float64) float64 {
+	switch c.RadiusEstimation {
+	case EstimateQuantileNN:
+		q := c.RadiusQuantile
+		if q <= 0 || q > 1 {
+			q = 0.9
+		}
+		distances := nnDistances(data, c.distanceFunc())
+		sort.Float64s(distances)
+		return distances[int(math.Ceil(q*float64(len(distances))))-1]
+
+	case EstimateTargetMC:
+		if c.TargetMC <= 0 || c.TargetMC >= len(data) {
+			break
+		}
+		// recherche dichotomique : le nombre de µC décroît lorsque le rayon augmente
+		low, high := 0.0, 0.0
+		for _, v := range data {
+			high = math.Max(high, c.distanceFunc()(data[0], v))
+		}
+		high *= 2
+		for i := 0; i < 30; i++ {
+			radius := (low + high) / 2
+			cl := NewClusterer(radius, c.threshold, c.zones, c.outlier)
+			if c.distFunction != "" {
+				cl.SetDistanceFunction(c.distFunction)
+			}
+			cl.Add(data)
+			if cl.CountMC() > c.TargetMC {
+				low = radius
+			} else {
+				high = radius
+			}
+		}
+		return high
+	}
+
+	mean, std := nnStats(data, c.distanceFunc())
+	return mean + 2*std
+}
@@ -0,0 +1,314 @@
+package microClustering
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+/*
+  Evaluation des classifieurs
+
+  - métriques : accuracy, précision / rappel / F1 par classe, matrice de confusion, log-loss
+  - validation croisée en k plis (simple ou stratifiée), chaque pli utilisant un nouveau Classifier
+  - évaluation prequential (test-then-train) pour les flux : chaque mesure est prédite puis apprise
+*/
+
+// Accuracy renvoie la proportion de prédictions correctes
+func Accuracy(yTrue, yPred []int) float64 {
+	if len(yTrue) == 0 || len(yTrue) != len(yPred) {
+		return 0
+	}
+	ok := 0
+	for i := range yTrue {
+		if yTrue[i] == yPred[i] {
+			ok++
+		}
+	}
+	return float64(ok) / float64(len(yTrue))
+}
+
+// ConfusionMatrix compte les prédictions par classe réelle (lignes) et classe prédite (colonnes)
+type ConfusionMatrix struct {
+	Labels []int   // libellés triés, l'indice est celui des lignes et colonnes de Counts
+	Counts [][]int // Counts[i][j] : nombre de mesures de la classe Labels[i] prédites Labels[j]
+}
+
+// ClassMetrics regroupe les métriques d'une classe
+type ClassMetrics struct {
+	Precision float64
+	Recall    float64
+	F1        float64
+	Support   int // nombre de mesures de la classe
+}
+
+// NewConfusionMatrix calcule la matrice de confusion des prédictions 'yPred'
+func NewConfusionMatrix(yTrue, yPred []int) ConfusionMatrix {
+	m := ConfusionMatrix{}
+	m.Add(yTrue, yPred)
+	return m
+}
+
+// index renvoie l'indice du libellé dans la matrice, en l'ajoutant si nécessaire
+func (m *ConfusionMatrix) index(label int) int {
+	i := sort.SearchInts(m.Labels, label)
+	if i < len(m.Labels) && m.Labels[i] == label {
+		return i
+	}
+	m.Labels = append(m.Labels, 0)
+	copy(m.Labels[i+1:], m.Labels[i:])
+	m.Labels[i] = label
+
+	// insère une ligne et une colonne
+	for r := range m.Counts {
+		m.Counts[r] = append(m.Counts[r], 0)
+		copy(m.Counts[r][i+1:], m.Counts[r][i:])
+		m.Counts[r][i] = 0
+	}
+	m.Counts = append(m.Counts, nil)
+	copy(m.Counts[i+1:], m.Counts[i:])
+	m.Counts[i] = make([]int, len(m.Labels))
+	return i
+}
+
+// Add ajoute des prédictions à la matrice
+func (m *ConfusionMatrix) Add(yTrue, yPred []int) {
+	for i := range yTrue {
+		if i >= len(yPred) {
+			break
+		}
+		t := m.index(yTrue[i])
+		p := m.index(yPred[i])
+		m.Counts[t][p]++
+	}
+}
+
+// Accuracy renvoie la proportion de prédictions correctes
+func (m ConfusionMatrix) Accuracy() float64 {
+	ok, total := 0, 0
+	for i := range m.Counts {
+		for j, n := range m.Counts[i] {
+			total += n
+			if i == j {
+				ok += n
+			}
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(ok) / float64(total)
+}
+
+// Metrics renvoie la précision, le rappel et le F1 de chaque classe
+func (m ConfusionMatrix) Metrics() map[int]ClassMetrics {
+	metrics := make(map[int]ClassMetrics)
+	for i, label := range m.Labels {
+		tp := m.Counts[i][i]
+		predicted, support := 0, 0
+		for j := range m.Labels {
+			predicted += m.Counts[j][i]
+			support += m.Counts[i][j]
+		}
+		cm := ClassMetrics{Support: support}
+		if predicted > 0 {
+			cm.Precision = float64(tp) / float64(predicted)
+		}
+		if support > 0 {
+			cm.Recall = float64(tp) / float64(support)
+		}
+		if cm.Precision+cm.Recall > 0 {
+			cm.F1 = 2 * cm.Precision * cm.Recall / (cm.Precision + cm.Recall)
+		}
+		metrics[label] = cm
+	}
+	return metrics
+}
+
+// String affiche la matrice de confusion
+func (m ConfusionMatrix) String() string {
+	s := "true\\pred"
+	for _, label := range m.Labels {
+		s += fmt.Sprintf("\t%d", label)
+	}
+	for i, label := range m.Labels {
+		s += fmt.Sprintf("\n%d", label)
+		for _, n := range m.Counts[i] {
+			s += fmt.Sprintf("\t%d", n)
+		}
+	}
+	return s
+}
+
+// LogLoss renvoie l'entropie croisée moyenne des probabilités prédites 'proba' (voir PredictProba)
+// Les probabilités sont bornées à [1e-15, 1-1e-15]
+func LogLoss(yTrue []int, proba []map[int]float64) float64 {
+	if len(yTrue) == 0 || len(yTrue) != len(proba) {
+		return 0
+	}
+	const eps = 1e-15
+	loss := 0.0
+	for i, y := range yTrue {
+		p := math.Min(math.Max(proba[i][y], eps), 1-eps)
+		loss -= math.Log(p)
+	}
+	return loss / float64(len(yTrue))
+}
+
+// checkFolds vérifie que 'n' mesures peuvent être réparties en 'k' plis non vides
+func checkFolds(n, k int) error {
+	if k < 2 {
+		return fmt.Errorf("at least 2 folds are required, got %d", k)
+	}
+	if k > n {
+		return fmt.Errorf("%d folds for %d points", k, n)
+	}
+	return nil
+}
+
+// KFold répartit aléatoirement les indices 0..n-1 en k plis de tailles équivalentes (2 <= k <= n)
+func KFold(n, k int) (folds [][]int, err error) {
+	if err := checkFolds(n, k); err != nil {
+		return nil, err
+	}
+	folds = make([][]int, k)
+	for i, id := range rand.Perm(n) {
+		folds[i%k] = append(folds[i%k], id)
+	}
+	return folds, nil
+}
+
+// StratifiedKFold répartit les indices de 'Y' en k plis respectant les proportions de chaque classe (2 <= k <= len(Y))
+func StratifiedKFold(Y []int, k int) (folds [][]int, err error) {
+	if err := checkFolds(len(Y), k); err != nil {
+		return nil, err
+	}
+	folds = make([][]int, k)
+	byClass := make(map[int][]int)
+	for i, y := range Y {
+		byClass[y] = append(byClass[y], i)
+	}
+	labels := []int{}
+	for label := range byClass {
+		labels = append(labels, label)
+	}
+	sort.Ints(labels)
+
+	next := 0 // les classes se suivent pour équilibrer la taille des plis
+	for _, label := range labels {
+		ids := byClass[label]
+		rand.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+		for _, id := range ids {
+			folds[next%k] = append(folds[next%k], id)
+			next++
+		}
+	}
+	return folds, nil
+}
+
+// CVResult regroupe les résultats d'une validation croisée
+type CVResult struct {
+	Accuracy  []float64       // accuracy de chaque pli
+	Mean      float64         // accuracy moyenne
+	Std       float64         // écart-type de l'accuracy
+	LogLoss   float64         // log-loss sur l'ensemble des plis
+	Confusion ConfusionMatrix // matrice de confusion cumulée sur l'ensemble des plis
+}
+
+// CrossValidate évalue par validation croisée un classifieur créé par 'newClassifier' pour chaque pli.
+// Les plis sont des listes d'indices de X (voir KFold et StratifiedKFold), k est le nombre de voisins utilisé par KNN
+func CrossValidate(X [][]float64, Y []int, folds [][]int, newClassifier func() *Classifier, k int) (result CVResult, err error) {
+	if len(X) != len(Y) {
+		return result, fmt.Errorf("data and label mismatch")
+	}
+	if len(folds) < 2 {
+		return result, fmt.Errorf("at least 2 folds are required")
+	}
+
+	var (
+		allTrue  []int
+		allProba []map[int]float64
+	)
+	for f, test := range folds {
+		// jeu d'apprentissage : tous les autres plis
+		var trainX [][]float64
+		var trainY []int
+		for g, fold := range folds {
+			if g == f {
+				continue
+			}
+			for _, id := range fold {
+				trainX = append(trainX, X[id])
+				trainY = append(trainY, Y[id])
+			}
+		}
+		testX := make([][]float64, len(test))
+		testY := make([]int, len(test))
+		for i, id := range test {
+			testX[i] = X[id]
+			testY[i] = Y[id]
+		}
+
+		c := newClassifier()
+		if err := c.FitXY(trainX, trainY); err != nil {
+			return result, err
+		}
+		pred := c.KNN(testX, k)
+		result.Accuracy = append(result.Accuracy, Accuracy(testY, pred))
+		result.Confusion.Add(testY, pred)
+		for _, x := range testX {
+			allProba = append(allProba, c.PredictProba(x, k))
+		}
+		allTrue = append(allTrue, testY...)
+	}
+
+	for _, a := range result.Accuracy {
+		result.Mean += a
+	}
+	result.Mean /= float64(len(result.Accuracy))
+	for _, a := range result.Accuracy {
+		result.Std += math.Pow(a-result.Mean, 2)
+	}
+	result.Std = math.Sqrt(result.Std / float64(len(result.Accuracy)))
+	result.LogLoss = LogLoss(allTrue, allProba)
+	return result, nil
+}
+
+// PrequentialResult regroupe les résultats d'une évaluation prequential
+type PrequentialResult struct {
+	Predictions []int           // prédiction de chaque mesure évaluée
+	Accuracy    float64         // accuracy cumulée
+	Curve       []float64       // accuracy cumulée après chaque mesure évaluée
+	Confusion   ConfusionMatrix // matrice de confusion des mesures évaluées
+}
+
+// Prequential évalue le classifieur sur un flux (test-then-train) : chaque mesure est d'abord prédite puis apprise
+// par PartialFit. Les mesures arrivant avant que le classifieur ne connaisse une classe ne sont pas évaluées.
+// Les résultats des prédictions sont transmis au DriftMonitor du flux (voir ObservePrediction).
+// L'évaluation s'arrête à la première erreur de PartialFit.
+func Prequential(c *Classifier, X [][]float64, Y []int, k int) (result PrequentialResult, err error) {
+	if len(X) != len(Y) {
+		return result, fmt.Errorf("data and label mismatch")
+	}
+	ok := 0
+	for i, x := range X {
+		if len(c.classes) > 0 {
+			pred := c.KNN([][]float64{x}, k)[0]
+			result.Predictions = append(result.Predictions, pred)
+			result.Confusion.Add([]int{Y[i]}, []int{pred})
+			c.ObservePrediction(Y[i], pred)
+			if pred == Y[i] {
+				ok++
+			}
+			result.Curve = append(result.Curve, float64(ok)/float64(len(result.Curve)+1))
+		}
+		if err := c.PartialFit(x, Y[i]); err != nil {
+			return result, err
+		}
+	}
+	if len(result.Curve) > 0 {
+		result.Accuracy = result.Curve[len(result.Curve)-1]
+	}
+	return result, nil
+}
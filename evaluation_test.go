@@ -0,0 +1,200 @@
+package microClustering
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// blobs génère n points par classe autour de centres distincts
+func blobs(n int, centers [][]float64, spread float64) (X [][]float64, Y []int) {
+	for i := 0; i < n; i++ {
+		for label, center := range centers {
+			x := make([]float64, len(center))
+			for d := range center {
+				x[d] = center[d] + spread*rand.NormFloat64()
+			}
+			X = append(X, x)
+			Y = append(Y, label)
+		}
+	}
+	return X, Y
+}
+
+func TestMetrics(t *testing.T) {
+
+	yTrue := []int{1, 1, 1, 2, 2, 3}
+	yPred := []int{1, 1, 2, 2, 2, 1}
+
+	if a := Accuracy(yTrue, yPred); math.Abs(a-4.0/6) > 1e-9 {
+		t.Errorf("accuracy=%f", a)
+	}
+
+	m := NewConfusionMatrix(yTrue, yPred)
+	fmt.Println(m)
+	if m.Counts[0][0] != 2 || m.Counts[0][1] != 1 || m.Counts[2][0] != 1 {
+		t.Errorf("confusion matrix : %v", m.Counts)
+	}
+	metrics := m.Metrics()
+	if math.Abs(metrics[1].Precision-2.0/3) > 1e-9 || math.Abs(metrics[1].Recall-2.0/3) > 1e-9 || metrics[1].Support != 3 {
+		t.Errorf("class 1 : %+v", metrics[1])
+	}
+	if metrics[2].Recall != 1 || metrics[3].F1 != 0 {
+		t.Errorf("metrics : %+v", metrics)
+	}
+
+	loss := LogLoss([]int{1, 2}, []map[int]float64{{1: 1, 2: 0}, {1: 0.5, 2: 0.5}})
+	if math.Abs(loss-math.Log(2)/2) > 1e-9 {
+		t.Errorf("log-loss=%f", loss)
+	}
+}
+
+func TestCrossValidate(t *testing.T) {
+
+	X, Y := blobs(30, [][]float64{{0, 0}, {5, 5}, {0, 5}}, 0.3)
+
+	SetDistanceFunction("euclidian")
+	newClassifier := func() *Classifier {
+		return NewClassifier(0, 0, 1, 1, 3.0)
+	}
+
+	for _, k := range []int{0, 1, len(X) + 1} {
+		if _, err := KFold(len(X), k); err == nil {
+			t.Errorf("KFold with %d folds should fail", k)
+		}
+		if _, err := StratifiedKFold(Y, k); err == nil {
+			t.Errorf("StratifiedKFold with %d folds should fail", k)
+		}
+	}
+
+	kfold, _ := KFold(len(X), 5)
+	stratified, _ := StratifiedKFold(Y, 5)
+	for _, folds := range [][][]int{kfold, stratified} {
+		result, err := CrossValidate(X, Y, folds, newClassifier, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Println("accuracy=", result.Mean, "+/-", result.Std, " log-loss=", result.LogLoss)
+		if result.Mean < 0.9 {
+			t.Errorf("cross-validation accuracy %f", result.Mean)
+		}
+	}
+
+	// chaque classe est présente dans chaque pli stratifié
+	for _, fold := range stratified {
+		seen := make(map[int]bool)
+		for _, id := range fold {
+			seen[Y[id]] = true
+		}
+		if len(seen) != 3 {
+			t.Errorf("stratified fold without every class : %v", seen)
+		}
+	}
+}
+
+func TestPrequential(t *testing.T) {
+
+	X, Y := blobs(100, [][]float64{{0, 0}, {5, 5}}, 0.3)
+
+	SetDistanceFunction("euclidian")
+	c := NewClassifier(0, 0.5, 1, 1, 3.0)
+	result, err := Prequential(c, X, Y, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println("prequential accuracy=", result.Accuracy)
+	if len(result.Predictions) != len(X)-1 || result.Accuracy < 0.9 {
+		t.Errorf("prequential : %d predictions, accuracy %f", len(result.Predictions), result.Accuracy)
+	}
+}
+
+func TestSearch(t *testing.T) {
+
+	// classe 0 formée de deux groupes encadrant la classe 1, de même poids total : un rayon englobant toute la
+	// classe 0 place son µC entre les deux groupes, plus loin du groupe {3, 3} que le µC de la classe 1
+	rnd := rand.New(rand.NewSource(1))
+	X, Y := [][]float64{}, []int{}
+	for i := 0; i < 20; i++ {
+		for label, center := range [][]float64{{3 * float64(i%2), 3 * float64(i%2)}, {2, 2}} {
+			X = append(X, []float64{center[0] + 0.25*rnd.NormFloat64(), center[1] + 0.25*rnd.NormFloat64()})
+			Y = append(Y, label)
+		}
+	}
+
+	space := SearchSpace{
+		Radius:   []float64{0, 0.2, 10},
+		K:        []int{1, 3},
+		Distance: []string{"euclidian", "manhattan"},
+	}
+
+	report, err := GridSearch(X, Y, space, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Results) != 12 {
+		t.Errorf("grid search : %d results", len(report.Results))
+	}
+	for i := 1; i < len(report.Results); i++ {
+		if report.Results[i].CV.Mean > report.Results[i-1].CV.Mean {
+			t.Error("results not ranked")
+		}
+	}
+	fmt.Println("best :", report.BestParams, " accuracy=", report.Results[0].CV.Mean)
+	if report.BestParams.Radius == 10 {
+		t.Error("a radius larger than the data should not win")
+	}
+	for _, result := range report.Results {
+		if result.Params.Radius == 10 && result.CV.Mean >= report.Results[0].CV.Mean-0.2 {
+			t.Errorf("radius 10 accuracy %f close to the best %f", result.CV.Mean, report.Results[0].CV.Mean)
+		}
+	}
+	if y := report.Best.KNN([][]float64{{2, 2}}, report.BestParams.K); y[0] != 1 {
+		t.Errorf("best classifier : %v", y)
+	}
+
+	report, err = RandomSearch(X, Y, space, 3, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Results) != 4 {
+		t.Errorf("random search : %d results", len(report.Results))
+	}
+	if _, err := RandomSearch(X, Y, space, 3, -1); err == nil {
+		t.Error("random search with a negative number of iterations accepted")
+	}
+
+	// les réglages du classifieur de base sont conservés, les zones et le seuil d'outliers sont recherchés
+	parzen := SearchSpace{
+		Radius:  []float64{0.5},
+		Zones:   []int{1, 4},
+		Outlier: []float64{1, 3},
+		K:       []int{3},
+		Base: func() *Classifier {
+			c := NewClassifier(0, 0, 1, 1, 3)
+			c.Decision = ParzenDecision
+			c.CheckOutliers = true
+			return c
+		},
+	}
+	report, err = GridSearch(X, Y, parzen, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Results) != 4 {
+		t.Errorf("parzen grid search : %d results", len(report.Results))
+	}
+	if report.Best.Decision != ParzenDecision || !report.Best.CheckOutliers {
+		t.Error("base classifier settings not kept")
+	}
+	if report.Best.zones != report.BestParams.Zones || report.Best.outlier != report.BestParams.Outlier {
+		t.Errorf("best classifier zones=%d outlier=%g, expected %v", report.Best.zones, report.Best.outlier, report.BestParams)
+	}
+
+	if _, err := GridSearch(X, Y, SearchSpace{Distance: []string{"unknown"}}, 3); err == nil {
+		t.Error("unknown distance function accepted")
+	}
+	if _, err := GridSearch(X, Y, space, 0); err == nil {
+		t.Error("grid search without folds accepted")
+	}
+}
@@ -0,0 +1,280 @@
+package microClustering
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+/*
+  Classification hiérarchique
+
+  - les labels forment un arbre (LabelTree) : par exemple service -> endpoint
+  - chaque noeud interne de l'arbre dispose d'un Classifier qui choisit parmi ses enfants,
+    un Classifier racine choisit parmi les labels sans parent
+  - chaque mesure est apprise par les Classifier de tous les noeuds du chemin menant à son label
+  - la prédiction descend l'arbre depuis la racine : si la probabilité du meilleur enfant est inférieure à
+    MinConfidence, la prédiction s'arrête au noeud parent
+*/
+
+// LabelTree décrit la hiérarchie des labels : chaque label a au plus un parent
+type LabelTree struct {
+	parent map[int]int
+}
+
+// NewLabelTree crée un arbre de labels vide
+func NewLabelTree() *LabelTree {
+	return &LabelTree{parent: make(map[int]int)}
+}
+
+// AddEdge déclare 'child' comme enfant de 'parent'
+func (t *LabelTree) AddEdge(parent, child int) error {
+	if p, exists := t.parent[child]; exists && p != parent {
+		return fmt.Errorf("label %d already has parent %d", child, p)
+	}
+	for _, label := range t.Path(parent) {
+		if label == child {
+			return fmt.Errorf("edge %d -> %d creates a cycle", parent, child)
+		}
+	}
+	t.parent[child] = parent
+	return nil
+}
+
+// Parent renvoie le parent du label, false si le label est une racine
+func (t *LabelTree) Parent(label int) (int, bool) {
+	p, exists := t.parent[label]
+	return p, exists
+}
+
+// Children renvoie les enfants triés du label
+func (t *LabelTree) Children(label int) (children []int) {
+	for child, p := range t.parent {
+		if p == label {
+			children = append(children, child)
+		}
+	}
+	sort.Ints(children)
+	return children
+}
+
+// Path renvoie le chemin de la racine jusqu'au label (inclus)
+func (t *LabelTree) Path(label int) []int {
+	path := []int{label}
+	for {
+		p, exists := t.parent[label]
+		if !exists {
+			break
+		}
+		path = append([]int{p}, path...)
+		label = p
+	}
+	return path
+}
+
+// Hierarchical prédit un label en descendant l'arbre des labels
+type Hierarchical struct {
+	Tree          *LabelTree
+	MinConfidence float64   // probabilité minimum du meilleur enfant pour descendre d'un niveau (0 : toujours descendre)
+	UnknownLabel  int       // label renvoyé si la racine n'est pas assez sûre (-1 par défaut)
+	Weighting     Weighting // pondération du vote des noeuds (inverse de la distance par défaut)
+	Radius        float64   // rayon des µC de chaque noeud (0 : estimé pour chaque noeud)
+	threshold     int
+	zones         int
+	outlier       float64
+
+	root  *Classifier         // choisit parmi les labels racines
+	nodes map[int]*Classifier // choisit parmi les enfants de chaque noeud interne
+}
+
+// NewHierarchical crée un classifieur hiérarchique suivant l'arbre de labels 'tree'
+func NewHierarchical(tree *LabelTree, radius float64, threshold int, zones int, outlier float64) *Hierarchical {
+	return &Hierarchical{
+		Tree:         tree,
+		UnknownLabel: -1,
+		Weighting:    InverseDistanceWeighting,
+		Radius:       radius,
+		threshold:    threshold,
+		zones:        zones,
+		outlier:      outlier,
+		nodes:        make(map[int]*Classifier),
+	}
+}
+
+// node renvoie le Classifier choisissant parmi les enfants de 'parent' (la racine si isRoot), en le créant si nécessaire
+func (h *Hierarchical) node(parent int, isRoot bool) *Classifier {
+	if isRoot {
+		if h.root == nil {
+			h.root = NewClassifier(0, h.Radius, h.threshold, h.zones, h.outlier)
+			h.root.Weighting = h.Weighting
+		}
+		return h.root
+	}
+	c, exists := h.nodes[parent]
+	if !exists {
+		c = NewClassifier(0, h.Radius, h.threshold, h.zones, h.outlier)
+		c.Weighting = h.Weighting
+		h.nodes[parent] = c
+	}
+	return c
+}
+
+// FitXY réalise l'apprentissage des données 'X' dont les labels sont précisés dans 'Y' :
+// chaque noeud apprend les mesures des labels situés sous chacun de ses enfants
+func (h *Hierarchical) FitXY(X [][]float64, Y []int) error {
+	if len(X) != len(Y) {
+		return fmt.Errorf("data and label mismatch")
+	}
+	type group struct {
+		X [][]float64
+		Y []int
+	}
+	root := group{}
+	groups := make(map[int]*group)
+	for i, y := range Y {
+		path := h.Tree.Path(y)
+		root.X = append(root.X, X[i])
+		root.Y = append(root.Y, path[0])
+		for d := 1; d < len(path); d++ {
+			g, exists := groups[path[d-1]]
+			if !exists {
+				g = &group{}
+				groups[path[d-1]] = g
+			}
+			g.X = append(g.X, X[i])
+			g.Y = append(g.Y, path[d])
+		}
+	}
+
+	if len(root.X) > 0 {
+		if err := h.node(0, true).FitXY(root.X, root.Y); err != nil {
+			return err
+		}
+	}
+	for parent, g := range groups {
+		if err := h.node(parent, false).FitXY(g.X, g.Y); err != nil {
+			return fmt.Errorf("node %d: %v", parent, err)
+		}
+	}
+	return nil
+}
+
+// PartialFit apprend une mesure 'x' du label 'y' dans tous les noeuds du chemin menant au label
+func (h *Hierarchical) PartialFit(x []float64, y int) error {
+	path := h.Tree.Path(y)
+	if err := h.node(0, true).PartialFit(x, path[0]); err != nil {
+		return err
+	}
+	for d := 1; d < len(path); d++ {
+		if err := h.node(path[d-1], false).PartialFit(x, path[d]); err != nil {
+			return fmt.Errorf("node %d: %v", path[d-1], err)
+		}
+	}
+	return nil
+}
+
+// PredictPath renvoie le chemin prédit pour 'x' depuis la racine, ainsi que la probabilité de chaque étape.
+// La descente s'arrête lorsque la probabilité du meilleur enfant est inférieure à MinConfidence.
+func (h *Hierarchical) PredictPath(x []float64, k int) (path []int, confidence []float64) {
+	c := h.root
+	for c != nil && len(c.classes) > 0 {
+		proba := c.PredictProba(x, k)
+		best := bestClass(proba, nil)
+		if proba[best] < h.MinConfidence {
+			break
+		}
+		path = append(path, best)
+		confidence = append(confidence, proba[best])
+		c = h.nodes[best]
+	}
+	return path, confidence
+}
+
+// Predict renvoie le label prédit pour chaque vecteur de 'x' : le label le plus profond atteint avec une confiance
+// suffisante, UnknownLabel si la racine n'est pas assez sûre
+func (h *Hierarchical) Predict(x [][]float64, k int) (y []int) {
+	for _, vector := range x {
+		path, _ := h.PredictPath(vector, k)
+		if len(path) == 0 {
+			y = append(y, h.UnknownLabel)
+			continue
+		}
+		y = append(y, path[len(path)-1])
+	}
+	return y
+}
+
+type hierarchicalJSON struct {
+	Parents       map[int]int             `json:"parents"` // parent de chaque label non racine
+	MinConfidence float64                 `json:"min_confidence,omitempty"`
+	UnknownLabel  int                     `json:"unknown_label"`
+	Weighting     Weighting               `json:"weighting"`
+	Radius        float64                 `json:"radius"`
+	Threshold     int                     `json:"threshold"`
+	Zones         int                     `json:"zones"`
+	Outlier       float64                 `json:"outlier"`
+	Root          json.RawMessage         `json:"root,omitempty"`
+	Nodes         map[int]json.RawMessage `json:"nodes,omitempty"`
+}
+
+// ToJson exporte le classifieur hiérarchique en JSON
+func (h *Hierarchical) ToJson() ([]byte, error) {
+	toExport := hierarchicalJSON{
+		Parents:       h.Tree.parent,
+		MinConfidence: h.MinConfidence,
+		UnknownLabel:  h.UnknownLabel,
+		Weighting:     h.Weighting,
+		Radius:        h.Radius,
+		Threshold:     h.threshold,
+		Zones:         h.zones,
+		Outlier:       h.outlier,
+		Nodes:         make(map[int]json.RawMessage),
+	}
+	if h.root != nil {
+		d, err := h.root.ToJson()
+		if err != nil {
+			return nil, err
+		}
+		toExport.Root = d
+	}
+	for label, c := range h.nodes {
+		d, err := c.ToJson()
+		if err != nil {
+			return nil, err
+		}
+		toExport.Nodes[label] = d
+	}
+	return json.Marshal(toExport)
+}
+
+// NewHierarchicalFromJson crée un classifieur hiérarchique à partir d'un export JSON
+func NewHierarchicalFromJson(data []byte) (*Hierarchical, error) {
+	toImport := hierarchicalJSON{UnknownLabel: -1}
+	if err := json.Unmarshal(data, &toImport); err != nil {
+		return nil, err
+	}
+
+	tree := NewLabelTree()
+	for child, parent := range toImport.Parents {
+		tree.parent[child] = parent
+	}
+	h := NewHierarchical(tree, toImport.Radius, toImport.Threshold, toImport.Zones, toImport.Outlier)
+	h.MinConfidence = toImport.MinConfidence
+	h.UnknownLabel = toImport.UnknownLabel
+	h.Weighting = toImport.Weighting
+	if toImport.Root != nil {
+		root, err := NewClassifierFromJson(toImport.Root)
+		if err != nil {
+			return nil, err
+		}
+		h.root = root
+	}
+	for label, d := range toImport.Nodes {
+		c, err := NewClassifierFromJson(d)
+		if err != nil {
+			return nil, err
+		}
+		h.nodes[label] = c
+	}
+	return h, nil
+}
@@ -0,0 +1,141 @@
+package microClustering
+
+import (
+	"fmt"
+)
+
+// LabelEncoder associe un code de classe entier à chaque libellé textuel.
+// Le code d'un libellé est son rang d'apparition. Le libellé vide désigne un code sans libellé (classe supprimée ou
+// renommée).
+type LabelEncoder struct {
+	labels []string       // libellé de chaque classe, l'indice est le code de la classe
+	codes  map[string]int // code de chaque libellé
+}
+
+// NewLabelEncoder crée un encodeur contenant les libellés 'labels' dans cet ordre ; un libellé vide réserve son code
+func NewLabelEncoder(labels ...string) *LabelEncoder {
+	e := &LabelEncoder{codes: make(map[string]int)}
+	for _, label := range labels {
+		if label == "" {
+			e.labels = append(e.labels, "")
+			continue
+		}
+		e.encode(label)
+	}
+	return e
+}
+
+// Encode renvoie le code du libellé, ajouté à l'encodeur s'il est inconnu.
+// Le libellé vide est refusé : il désigne un code sans libellé
+func (e *LabelEncoder) Encode(label string) (int, error) {
+	if label == "" {
+		return -1, fmt.Errorf("empty label")
+	}
+	return e.encode(label), nil
+}
+
+// encode renvoie le code du libellé non vide 'label', ajouté à l'encodeur s'il est inconnu
+func (e *LabelEncoder) encode(label string) int {
+	code, exists := e.codes[label]
+	if !exists {
+		code = len(e.labels)
+		e.labels = append(e.labels, label)
+		e.codes[label] = code
+	}
+	return code
+}
+
+// Code renvoie le code du libellé et false si le libellé est inconnu
+func (e *LabelEncoder) Code(label string) (int, bool) {
+	code, exists := e.codes[label]
+	return code, exists
+}
+
+// Decode renvoie le libellé correspondant au code et false si le code est inconnu
+func (e *LabelEncoder) Decode(code int) (string, bool) {
+	if code < 0 || code >= len(e.labels) || e.labels[code] == "" {
+		return "", false
+	}
+	return e.labels[code], true
+}
+
+// Labels renvoie les libellés connus, dans l'ordre de leurs codes (vide pour un code sans libellé)
+func (e *LabelEncoder) Labels() []string {
+	return append([]string{}, e.labels...)
+}
+
+// retire supprime le libellé du code 'code'
+func (e *LabelEncoder) retire(code int) {
+	label, exists := e.Decode(code)
+	if !exists {
+		return
+	}
+	delete(e.codes, label)
+	e.labels[code] = ""
+}
+
+// recode associe le libellé du code 'old' au code 'new' ; le libellé est supprimé si 'new' est négatif
+func (e *LabelEncoder) recode(old, new int) {
+	label, exists := e.Decode(old)
+	if !exists {
+		return
+	}
+	e.retire(old)
+	if new < 0 {
+		return
+	}
+	e.retire(new)
+	for len(e.labels) <= new {
+		e.labels = append(e.labels, "")
+	}
+	e.labels[new] = label
+	e.codes[label] = new
+}
+
+// FitXYStrings réalise l'apprentissage des données 'X' dont les libellés textuels sont précisés dans 'labels'.
+// Les libellés, non vides, sont encodés par c.Labels puis les données sont apprises par FitXY
+func (c *Classifier) FitXYStrings(X [][]float64, labels []string) error {
+	if len(X) != len(labels) {
+		return fmt.Errorf("data and label mismatch")
+	}
+	for i, label := range labels { // aucun libellé n'est encodé si l'un d'eux est refusé
+		if label == "" {
+			return fmt.Errorf("empty label at row %d", i)
+		}
+	}
+	if c.Labels == nil {
+		c.Labels = NewLabelEncoder()
+	}
+	Y := make([]int, len(labels))
+	for i, label := range labels {
+		Y[i] = c.Labels.encode(label)
+	}
+	return c.FitXY(X, Y)
+}
+
+// KNNStrings renvoie les libellés textuels des classes les plus proches (voir KNN).
+// Les prédictions rejetées renvoient un libellé vide
+func (c *Classifier) KNNStrings(x [][]float64, k int) (labels []string) {
+	for _, y := range c.KNN(x, k) {
+		labels = append(labels, c.decode(y))
+	}
+	return labels
+}
+
+// PredictProbaStrings renvoie la probabilité de chaque libellé textuel pour le vecteur 'x' (voir PredictProba)
+func (c *Classifier) PredictProbaStrings(x []float64, k int) map[string]float64 {
+	proba := make(map[string]float64)
+	for y, p := range c.PredictProba(x, k) {
+		proba[c.decode(y)] = p
+	}
+	return proba
+}
+
+// decode renvoie le libellé textuel de la classe 'y', vide si la classe n'a pas de libellé
+func (c *Classifier) decode(y int) string {
+	if c.Labels == nil {
+		return ""
+	}
+	label, _ := c.Labels.Decode(y)
+	return label
+}
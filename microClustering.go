@@ -5,6 +5,7 @@ import (
 	"math"
 	"math/rand"
 	"sort"
+	"sync"
 	"time"
 )
 
@@ -24,6 +25,11 @@ import (
   - Un pourcentage d'oubli peut être appliqué à intervalles régulier dans le cas de jeu de données live pour adapter la segmentation à
     l'évolution de la population dans le temps. Les µC dont la taille passe en dessous du seuil de prise en compte sont supprimés.
   - Generation de jeu de données : génération d'un jeu de données de taille fixe respectant la typologie des points de mesure injectés.
+  - Concurrence : les méthodes publiques du Clusterer peuvent être appelées depuis plusieurs goroutines. L'apprentissage
+    prend le verrou en écriture, les requêtes (IsOutlier, KNN, MacroClusters...) le verrou en lecture ; Generate
+    travaille sur une copie des µC pour ne pas bloquer l'apprentissage. Les fonctions appelées par le Clusterer
+    (OnCapacity, OnDrift) le sont sous verrou et ne doivent pas appeler ses méthodes. Les modèles construits sur un
+    Clusterer (Regressor, SemiSupervised, MultiLabel...) prennent son verrou à chaque point d'entrée.
 
   - Algorithmes exploitant les µC:
       - kMeans : implémentation du clustering utilisant les µC au lieu des données brutes.
@@ -44,6 +50,19 @@ type microcluster struct {
 	Zones  []int     `json:"zones"`  // nombre de mesures par zone
 	Weight int       `json:"weight"` // poids du cluster
 
+	Radius     float64 `json:"radius,omitempty"`      // rayon propre du µC en mode de rayon adaptatif (0 : rayon global)
+	LastUpdate int64   `json:"last_update,omitempty"` // date (en nombre de mesures traitées) de la dernière mise à jour du µC
+
+	// cluster features (optionnelles) permettant des calculs exacts sur le µC (variance, fusion, suppression)
+	LS []float64 `json:"ls,omitempty"` // somme linéaire des mesures
+	SS []float64 `json:"ss,omitempty"` // somme des carrés des mesures
+
+	Target *targetStats    `json:"target,omitempty"` // statistiques de la variable cible des mesures (régression)
+	Labels map[int]float64 `json:"labels,omitempty"` // nombre de mesures étiquetées par classe (apprentissage semi-supervisé)
+
+	Exemplars []Exemplar `json:"exemplars,omitempty"` // échantillon (reservoir) de mesures réelles affectées au µC
+	Seen      int        `json:"seen,omitempty"`      // nombre de mesures présentées au reservoir
+
 	//kmeanId int       // numéro du clusters en clusterisation kmean
 
 }
@@ -67,18 +86,47 @@ type Clusterer struct {
 	distFunction string          //nom de la fonction distance utilisée
 	distance     DistanceFunc    // fonction utilisée pour évaluer les distances
 	mc           []*microcluster // liste de tous les microclusters créés
+
+	// reservoir d'exemples
+	ReservoirSize   int  // nombre maximum de mesures réelles conservées par µC (0 : pas de reservoir)
+	ExportExemplars bool // exporte les reservoirs dans le snapshot JSON
+
+	// rayon adaptatif
+	RadiusMode   RadiusMode // mode de calcul du rayon de chaque µC
+	RadiusFactor float64    // facteur appliqué à l'écart quadratique moyen en mode DeviationRadius (2 par défaut)
+	MaxRadius    float64    // rayon maximum d'un µC (0 : pas de limite)
+
+	// nombre maximum de µC
+	MaxMicroClusters int                 // nombre maximum de µC (0 : pas de limite)
+	CapacityPolicy   CapacityPolicy      // politique appliquée lorsque MaxMicroClusters est dépassé
+	OnCapacity       func(CapacityEvent) // appelée pour chaque µC fusionné ou supprimé pour respecter MaxMicroClusters
+	tick             int64               // nombre de mesures traitées
+
+	TrackFeatures bool          // maintient les cluster features (LS, SS) de chaque µC
+	PrivacyBudget PrivacyBudget // budget de confidentialité différentielle consommé par les exports privés
+
+	Drift  *DriftMonitor // détection de dérive sur les mesures apprises (nil : pas de détection)
+	Stream *Stream       // paramètres de Consume et ConsumeReader (nil : valeurs par défaut)
+
+	Seed int64 // graine du générateur aléatoire de Generate (0 : graine différente à chaque génération)
+
+	mu sync.RWMutex // verrou des µC : écriture pour l'apprentissage, lecture pour les requêtes
 }
 
 func (c *Clusterer) CountMC() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return len(c.mc)
 }
 
 //IsOutlier renvoie true si le point n'appartient a aucun µCluster représentatif
 func (c *Clusterer) IsOutlier(x []float64) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	threshold := c.mediumSize - c.outlierThreshold*c.sigmaSize
 	for _, mc := range c.mc { // Pour chaque µC
-		if float64(mc.Weight) > threshold { // S'il est représentatif
-			if c.distance(x, mc.Center) <= c.mcRadius { // tant que le point généré n'est pas dans la sphere
+		if float64(mc.Weight) >= threshold { // S'il est représentatif (un µC est un outlier si Weight < threshold)
+			if c.distance(x, mc.Center) <= c.radiusOf(mc) { // tant que le point généré n'est pas dans la sphere
 				return false
 			}
 		}
@@ -86,23 +134,52 @@ func (c *Clusterer) IsOutlier(x []float64) bool {
 	return true
 }
 
+// OutlierScore renvoie la distance du point au µC représentatif le plus proche, rapportée au rayon de ce µC :
+// un score supérieur à 1 correspond à un outlier au sens de IsOutlier (+Inf s'il n'y a aucun µC représentatif)
+func (c *Clusterer) OutlierScore(x []float64) float64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	threshold := c.mediumSize - c.outlierThreshold*c.sigmaSize
+	score := math.Inf(1)
+	for _, mc := range c.mc {
+		if float64(mc.Weight) >= threshold {
+			if s := c.distance(x, mc.Center) / c.radiusOf(mc); s < score {
+				score = s
+			}
+		}
+	}
+	return score
+}
+
+// newRand crée un générateur aléatoire de graine 'seed', ou d'une graine différente à chaque appel si 'seed' vaut 0
+func newRand(seed int64) *rand.Rand {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return rand.New(rand.NewSource(seed))
+}
+
 // Generate génére un jeu de données de 'size' éléments aléatoire respectant la distribution des µC représentatifs
 // Le jeu de données généré peut être légèrement plus grand que la taille demandée si la difference de taille entre les plus grands
-// et les plus petits clusters est très importante
+// et les plus petits clusters est très importante. Deux générations de même graine (Seed) produisent le même jeu de données.
 func (c *Clusterer) Generate(size int) (data [][]float64) {
+	return c.generate(size, newRand(c.Seed))
+}
+
+// generate réalise Generate avec le générateur aléatoire 'rnd'
+func (c *Clusterer) generate(size int, rnd *rand.Rand) (data [][]float64) {
+	mcs, distance := c.snapshot() // la génération n'utilise pas les µC partagés
 	totalSize := 0
 	//calcule le nombre d'elements
-	for _, mc := range c.mc {
+	for _, mc := range mcs {
 		if mc.Weight >= c.minSize {
 			totalSize += mc.Weight
 		}
 	}
 	//fmt.Println("Original size :", totalSize)
 
-	rand.Seed(time.Now().UnixNano())
-
 	//génération du jeu de données
-	for _, mc := range c.mc { // Pour chaque µC
+	for _, mc := range mcs { // Pour chaque µC
 
 		//fmt.Println("µC weight : ", mc.Weight, " minSize=", c.minSize)
 		if mc.Weight >= c.minSize { // S'il est représentatif
@@ -114,22 +191,38 @@ func (c *Clusterer) Generate(size int) (data [][]float64) {
 				nbToGenerate = 1
 			}
 			if nbToGenerate > 0 {
-				data = append(data, mc.Generate(nbToGenerate, c.mcRadius, c.distance)...)
+				data = append(data, mc.Generate(nbToGenerate, mc.Radius, distance, rnd)...)
 			}
 		}
 	}
 
 	// Si le nombre de points générés est inférieur au nombre de points demandé, ajoute autant de points que nécessaire
 	for len(data) < size {
-		mcid := rand.Intn(len(c.mc))
-		if c.mc[mcid].Weight >= c.minSize {
-			data = append(data, c.mc[mcid].Generate(1, c.mcRadius, c.distance)...)
+		mcid := rnd.Intn(len(mcs))
+		if mcs[mcid].Weight >= c.minSize {
+			data = append(data, mcs[mcid].Generate(1, mcs[mcid].Radius, distance, rnd)...)
 		}
 	}
 
 	return data
 }
 
+// snapshot copie sous verrou les centres, zones, poids et rayons des µC ainsi que la fonction distance
+func (c *Clusterer) snapshot() ([]*microcluster, DistanceFunc) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	mcs := make([]*microcluster, len(c.mc))
+	for i, mc := range c.mc {
+		mcs[i] = &microcluster{
+			Center: append([]float64{}, mc.Center...),
+			Zones:  append([]int{}, mc.Zones...),
+			Weight: mc.Weight,
+			Radius: c.radiusOf(mc),
+		}
+	}
+	return mcs, c.distance
+}
+
 /*func (c *Clusterer) Generate(size int) (data [][]float64) {
 	totalSize := 0
 	//calcule le nombre d'elements
@@ -203,7 +296,22 @@ func NewClusterer(radius float64, minSize int, zones int, outlierThreshold float
 	return clusterer
 }
 
+// SetDistanceFunction définit la fonction distance utilisée par le Clusterer, indépendamment de la fonction distance globale
+func (c *Clusterer) SetDistanceFunction(name string) error {
+	f, exists := distanceFunctions[name]
+	if !exists {
+		return fmt.Errorf("unknown distance function %q", name)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.distFunction = name
+	c.distance = f
+	return nil
+}
+
 func (c *Clusterer) Stats() {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	fmt.Println("nb µClusters : ", len(c.mc))
 
 	moy := 0
@@ -225,32 +333,102 @@ func (c *Clusterer) Stats() {
 
 // recherche un cluster pour chaque point
 func (c *Clusterer) Add(m [][]float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.addPoints(m, nil, nil)
+}
+
+// AddWithPayload ajoute les mesures de 'm' en associant à chacune une donnée opaque (identifiant, enregistrement d'origine...)
+// conservée avec la mesure si elle est retenue dans le reservoir d'exemples du µC
+func (c *Clusterer) AddWithPayload(m [][]float64, payloads []interface{}) error {
+	if len(m) != len(payloads) {
+		return fmt.Errorf("data and payload mismatch")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.addPoints(m, payloads, nil)
+	return nil
+}
+
+// addPoints ajoute les mesures de 'm' ; si 'absorb' est précisée, elle est appelée avec le µC qui absorbe chaque mesure
+// avant que le nombre maximum de µC ne soit appliqué
+func (c *Clusterer) addPoints(m [][]float64, payloads []interface{}, absorb func(i int, mc *microcluster)) {
 	if c.vectorSize == 0 {
 		c.vectorSize = len(m[0])
 	}
+	if c.RadiusMode == DeviationRadius { // le rayon est calculé à partir des cluster features
+		c.TrackFeatures = true
+	}
 	for i := range m {
 		if m[i] == nil {
 			continue
 		}
+		c.tick++
 		clusterFound := false
 		for mc := range c.mc { // recherche dans les cluster nouvellement créés
 			distance := c.distance(c.mc[mc].Center, m[i])
-			if distance <= c.mcRadius {
-				c.mc[mc].add(m[i], distance, c.mcRadius)
+			if radius := c.radiusOf(c.mc[mc]); distance <= radius {
+				weight := c.mc[mc].Weight
+				c.mc[mc].add(m[i], distance, radius)
+				c.mc[mc].LastUpdate = c.tick
+				c.updateRadius(c.mc[mc])
+				c.mc[mc].sample(m[i], payload(payloads, i), c.ReservoirSize)
+				if absorb != nil {
+					absorb(i, c.mc[mc])
+				}
+				if c.Drift != nil {
+					c.observeDrift(false, weight)
+				}
 				clusterFound = true
 				break
 			}
 		}
 		if !clusterFound { //création d'un nouveau microcluster
-			newMc := microcluster{Center: m[i], Weight: 1}
+			center := make([]float64, len(m[i])) // le centre évolue : il ne doit pas partager la mesure de l'appelant
+			copy(center, m[i])
+			newMc := microcluster{Center: center, Weight: 1, LastUpdate: c.tick}
 			newMc.Zones = make([]int, c.zones)
 			newMc.Zones[0] = 1
+			if c.TrackFeatures {
+				newMc.initFeatures(m[i])
+			}
+			c.updateRadius(&newMc)
+			newMc.sample(m[i], payload(payloads, i), c.ReservoirSize)
+			if absorb != nil {
+				absorb(i, &newMc)
+			}
 			c.mc = append(c.mc, &newMc)
+			if c.MaxMicroClusters > 0 && len(c.mc) > c.MaxMicroClusters {
+				c.enforceCapacity()
+			}
+			if c.Drift != nil {
+				c.observeDrift(true, 0)
+			}
 		}
 	}
+	c.updateStats()
 	//fmt.Println("MC : ", len(c.mc))
 }
 
+// updateStats met à jour la moyenne et l'écart-type des poids des µC utilisés pour la détection des outliers
+func (c *Clusterer) updateStats() {
+	if len(c.mc) == 0 {
+		c.mediumSize, c.sigmaSize = 0, 0
+		return
+	}
+	sum := 0.0
+	for _, mc := range c.mc {
+		sum += float64(mc.Weight)
+	}
+	c.mediumSize = sum / float64(len(c.mc))
+
+	sd := 0.0
+	for _, mc := range c.mc {
+		sd += math.Pow(float64(mc.Weight)-c.mediumSize, 2)
+	}
+	c.sigmaSize = math.Sqrt(sd / float64(len(c.mc)))
+}
+
 //Add ajoute une mesure dans un microcluster
 //Add décale la position du centre du cluster vers le nouveau point ajouté en prennant en compte la pondération du µC
 // Si le µC ne contient qu'un seul point alors le nouveau centre sera à mi-distance entre le centre actuel et le nouveau point
@@ -270,9 +448,27 @@ func (mc *microcluster) add(m []float64, dist float64, radius float64) {
 	}
 	//fmt.Println(mc.Zones)
 	mc.Weight++
+	if mc.LS != nil {
+		for i := range m {
+			mc.LS[i] += m[i]
+			mc.SS[i] += m[i] * m[i]
+		}
+	}
+}
+
+// initFeatures initialise les cluster features d'un µC ne contenant que la mesure 'm'
+func (mc *microcluster) initFeatures(m []float64) {
+	mc.LS = make([]float64, len(m))
+	mc.SS = make([]float64, len(m))
+	for i := range m {
+		mc.LS[i] = m[i]
+		mc.SS[i] = m[i] * m[i]
+	}
 }
 
 func (c *Clusterer) PrintMicroClusters() {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	for i, mc := range c.mc {
 		fmt.Println(i, " - ", mc.Center, " weight=", mc.Weight)
 	}
@@ -281,6 +477,13 @@ func (c *Clusterer) PrintMicroClusters() {
 // RandomDelete supprime pct mesures dans les mc
 // chaque mesure à la probabilité p d'être supprimée
 func (c *Clusterer) RandomDelete(pct float64, p float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.randomDelete(pct, p)
+}
+
+// randomDelete réalise RandomDelete, le verrou étant déjà pris
+func (c *Clusterer) randomDelete(pct float64, p float64) {
 	// compte les mc
 	totalMc := 0
 	for i := range c.mc {
@@ -299,7 +502,14 @@ func (c *Clusterer) RandomDelete(pct float64, p float64) {
 			if c.mc[i].Weight > 0 { // si le mc contient encore des mesures
 				p := rand.Float64() * 100
 				if p <= proba { // proba de supprimer une mesure
+					if c.mc[i].LS != nil { // la mesure supprimée est inconnue : retire une mesure moyenne des cluster features
+						for d := range c.mc[i].LS {
+							c.mc[i].LS[d] -= c.mc[i].LS[d] / float64(c.mc[i].Weight)
+							c.mc[i].SS[d] -= c.mc[i].SS[d] / float64(c.mc[i].Weight)
+						}
+					}
 					c.mc[i].Weight--
+					c.updateRadius(c.mc[i])
 					z := 0
 					for { // sélectionne aléatoirement la zone dans laquelle supprimer le point
 						z = rand.Intn(c.zones)
@@ -331,6 +541,7 @@ func (c *Clusterer) RandomDelete(pct float64, p float64) {
 			nbMCdeleted++
 		}
 	}
+	c.updateStats()
 }
 
 func (mc *microcluster) generateVector(radius float64, distance DistanceFunc) (result []float64) {
@@ -435,7 +646,7 @@ func (mc *microcluster) generateVectorOld(radius float64, distance DistanceFunc)
 }
 
 //Generate crée nb points aleatoires dans le cluster de rayon "radius" en respectant la répartition dans les zones
-func (mc *microcluster) Generate(nb int, radius float64, distance DistanceFunc) (data [][]float64) {
+func (mc *microcluster) Generate(nb int, radius float64, distance DistanceFunc, rnd *rand.Rand) (data [][]float64) {
 	totalGenerated := 0
 	for z, zone := range mc.Zones {
 
@@ -445,8 +656,8 @@ func (mc *microcluster) Generate(nb int, radius float64, distance DistanceFunc)
 		radiusZone := radius * float64(z+1) / float64(len(mc.Zones))
 		radiusPrevZone := radius * float64(z) / float64(len(mc.Zones))
 		for nbZone > 0 {
-			r := radiusPrevZone + rand.Float64()*(radiusZone-radiusPrevZone) // génére aléatoirement un rayon dans la zone
-			vector := nSphere(mc.Center, r)
+			r := radiusPrevZone + rnd.Float64()*(radiusZone-radiusPrevZone) // génére aléatoirement un rayon dans la zone
+			vector := nSphere(mc.Center, r, rnd)
 			data = append(data, vector)
 			nbZone--
 		}
@@ -456,8 +667,8 @@ func (mc *microcluster) Generate(nb int, radius float64, distance DistanceFunc)
 	manque := nb - totalGenerated
 
 	for manque > 0 {
-		r := rand.Float64() * radius // génére aléatoirement un rayon dans la sphere
-		vector := nSphere(mc.Center, r)
+		r := rnd.Float64() * radius // génére aléatoirement un rayon dans la sphere
+		vector := nSphere(mc.Center, r, rnd)
 		data = append(data, vector)
 		manque--
 	}
@@ -480,14 +691,25 @@ func (m neighborList) Less(i, j int) bool {
 func (m neighborList) Swap(i, j int) { m[i], m[j] = m[j], m[i] }
 
 //KNN renvoie les k µC les plus proches
+// La distance de chaque µC est divisée par son poids : les µC les plus lourds sont favorisés
 func (c *Clusterer) KNN(x []float64, k int) (mc []neighbor) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.knn(x, k, true)
+}
+
+// knn renvoie les k µC les plus proches, la distance étant divisée par le poids des µC si 'scaled' est vrai
+func (c *Clusterer) knn(x []float64, k int, scaled bool) (mc []neighbor) {
 	var nb neighborList
 
 	// mesure la distance à chaque µc
 	for _, mc := range c.mc {
 
-		dist := Distance(x, mc.Center)
+		dist := c.distance(x, mc.Center) * c.mcRadius / c.radiusOf(mc) // distance relative au rayon propre du µC
 		w := float64(mc.Weight)
+		if !scaled {
+			w = 1
+		}
 		newNeighbor := neighbor{distance: dist / w, weight: mc.Weight}
 		//newNeighbor := neighbor{distance: Distance(x, mc.Center) / float64(mc.Weight), weight: mc.Weight}
 		nb = append(nb, newNeighbor)
@@ -580,6 +802,8 @@ func MeanNN(data [][]float64 /*, distance DistanceFunc*/) (mean, sd float64) {
 }
 
 func (c *Clusterer) Size() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	totalSize := 0
 	//calcule le nombre d'elements
 	for _, mc := range c.mc {
@@ -2,6 +2,7 @@ package microClustering
 
 import (
 	"fmt"
+	"math"
 	"testing"
 )
 
@@ -47,3 +48,172 @@ func TestMarshall(t *testing.T) {
 	fmt.Println("err:", err)
 	c2.PrintMicroClusters()
 }
+
+func TestAdaptiveRadius(t *testing.T) {
+
+	data := [][]float64{}
+	for i := 0; i < 100; i++ {
+		data = append(data, []float64{float64(i%10) * 0.1, float64(i/10) * 0.1})
+	}
+
+	SetDistanceFunction("euclidian")
+	fixed := NewClusterer(0.2, 1, 2, 2)
+	fixed.Add(data)
+
+	for _, mode := range []RadiusMode{WeightedRadius, DeviationRadius} {
+		c := NewClusterer(0.2, 1, 2, 2)
+		c.RadiusMode = mode
+		c.MaxRadius = 0.6
+		c.Add(data)
+		fmt.Println("mode", mode, ": µC=", c.CountMC(), " fixed=", fixed.CountMC())
+		if c.CountMC() > fixed.CountMC() {
+			t.Errorf("mode %d : %d µC > %d with fixed radius", mode, c.CountMC(), fixed.CountMC())
+		}
+		for _, mc := range c.mc {
+			if c.radiusOf(mc) > c.MaxRadius {
+				t.Errorf("mode %d : radius %f > max radius", mode, c.radiusOf(mc))
+			}
+		}
+		if c.IsOutlier([]float64{0.45, 0.45}) {
+			t.Errorf("mode %d : point inside the data flagged as outlier", mode)
+		}
+
+		js, _ := c.ToJson()
+		c2, err := NewClustererFromJson(js)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c2.RadiusMode != mode || c2.radiusOf(c2.mc[0]) != c.radiusOf(c.mc[0]) {
+			t.Errorf("mode %d : radius not persisted", mode)
+		}
+	}
+}
+
+func TestMaxMicroClusters(t *testing.T) {
+
+	data := [][]float64{{2.0, 2.0}, {1.0, 3.0}, {2.0, 8.0}, {2.0, 9.0}, {3, 8}, {4, 6}, {4, 7}, {4, 9}, {5, 7}, {5, 8}, {5, 9}, {6, 4}, {7, 5}, {9, 4}}
+
+	SetDistanceFunction("euclidian")
+	for _, policy := range []CapacityPolicy{MergeClosest, EvictOldest, EvictLightest, MergeNewest} {
+		c := NewClusterer(0.5, 1, 1, 2)
+		c.TrackFeatures = true
+		c.MaxMicroClusters = 4
+		c.CapacityPolicy = policy
+		events := 0
+		weight := 0
+		c.OnCapacity = func(e CapacityEvent) {
+			events++
+			if !e.Merged {
+				weight += e.Weight
+			}
+		}
+		c.Add(data)
+
+		if c.CountMC() != c.MaxMicroClusters {
+			t.Errorf("policy %d : %d µC", policy, c.CountMC())
+		}
+		if events != len(data)-c.MaxMicroClusters {
+			t.Errorf("policy %d : %d events", policy, events)
+		}
+		// les mesures sont conservées par fusion ou signalées lors de la suppression
+		total := 0
+		for _, mc := range c.mc {
+			total += mc.Weight
+		}
+		if total+weight != len(data) {
+			t.Errorf("policy %d : weight %d + %d evicted != %d", policy, total, weight, len(data))
+		}
+	}
+}
+
+func TestMergePolicies(t *testing.T) {
+	// deux µC proches puis un µC éloigné : MergeClosest fusionne la paire proche, MergeNewest déplace un voisin
+	data := [][]float64{{0, 0}, {1, 0}, {10, 0}}
+	expected := map[CapacityPolicy][][]float64{
+		MergeClosest: {{0.5, 0}, {10, 0}},
+		MergeNewest:  {{0, 0}, {5.5, 0}},
+	}
+	for policy, centers := range expected {
+		c := NewClusterer(0.4, 1, 1, 2)
+		c.SetDistanceFunction("euclidian")
+		c.TrackFeatures = true
+		c.MaxMicroClusters = 2
+		c.CapacityPolicy = policy
+		c.Add(data)
+		var got [][]float64
+		for _, mc := range c.mc {
+			got = append(got, mc.Center)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(centers) {
+			t.Errorf("policy %d : centers %v, expected %v", policy, got, centers)
+		}
+	}
+}
+
+func TestOutlierScore(t *testing.T) {
+	c := NewClusterer(1, 1, 1, 3)
+	c.SetDistanceFunction("euclidian")
+	c.Add([][]float64{{0, 0}, {0.5, 0}, {10, 0}})
+
+	for _, x := range [][]float64{{0, 0}, {10.5, 0}, {5, 0}, {0, 2}} {
+		score := c.OutlierScore(x)
+		if (score > 1) != c.IsOutlier(x) {
+			t.Errorf("%v: score %v does not match IsOutlier", x, score)
+		}
+	}
+	if score := c.OutlierScore([]float64{10, 3}); score != 3 {
+		t.Errorf("score %v, expected 3 radii from the nearest µC", score)
+	}
+	if score := NewClusterer(1, 1, 1, 3).OutlierScore([]float64{0, 0}); !math.IsInf(score, 1) {
+		t.Errorf("score %v without µC, expected +Inf", score)
+	}
+
+	// seuls les µC représentatifs comptent : le µC de poids 1 est en dessous du seuil 5.5-0.5*4.5
+	small := NewClusterer(1, 1, 1, 0.5)
+	small.SetDistanceFunction("euclidian")
+	small.Add([][]float64{{10, 0}})
+	for i := 0; i < 10; i++ {
+		small.Add([][]float64{{0, 0}})
+	}
+	if score := small.OutlierScore([]float64{10, 0}); score != 10 || !small.IsOutlier([]float64{10, 0}) {
+		t.Errorf("score %v on a non representative µC, expected 10", score)
+	}
+
+	// Classifier : plus petit score parmi les classes
+	cl := NewClassifier(0, 1, 1, 1, 3)
+	cl.SetDistanceFunction("euclidian")
+	if score := cl.OutlierScore([]float64{0, 0}); !math.IsInf(score, 1) {
+		t.Errorf("classifier score %v without class, expected +Inf", score)
+	}
+	cl.FitXY([][]float64{{0, 0}, {0, 0}, {4, 0}, {4, 0}}, []int{0, 0, 1, 1})
+	for x, expected := range map[[2]float64]float64{{0, 0.5}: 0.5, {3, 0}: 1, {2, 0}: 2, {4, 3}: 3} {
+		score := cl.OutlierScore(x[:])
+		if math.Abs(score-expected) > 1e-9 || (score > 1) != cl.IsOutlier(x[:]) {
+			t.Errorf("%v: classifier score %v, expected %v", x, score, expected)
+		}
+	}
+}
+
+func TestGenerateSeed(t *testing.T) {
+	c := NewClusterer(1, 1, 2, 3)
+	c.Add([][]float64{{0, 0}, {0.5, 0}, {10, 0}, {10, 0.5}})
+	c.Seed = 7
+	if fmt.Sprint(c.Generate(50)) != fmt.Sprint(c.Generate(50)) {
+		t.Error("generations with the same seed differ")
+	}
+	c.Seed = 8
+	first := fmt.Sprint(c.Generate(50))
+	c.Seed = 7
+	if first == fmt.Sprint(c.Generate(50)) {
+		t.Error("generations with different seeds are identical")
+	}
+
+	cl := NewClassifier(0, 1, 1, 1, 3)
+	cl.FitXY([][]float64{{0, 0}, {0.5, 0}, {10, 0}, {10, 0.5}}, []int{0, 0, 1, 1})
+	cl.Seed = 7
+	X1, Y1 := cl.Generate(30, Balanced)
+	X2, Y2 := cl.Generate(30, Balanced)
+	if fmt.Sprint(X1, Y1) != fmt.Sprint(X2, Y2) {
+		t.Error("classifier generations with the same seed differ")
+	}
+}
@@ -0,0 +1,228 @@
+package microClustering
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+/*
+  Classification multi-label
+
+  - les mesures sont regroupées en µC par un Clusterer unique
+  - chaque µC compte, pour chaque label, le nombre de mesures absorbées portant ce label
+  - un-contre-tous : la probabilité d'un label est la part des mesures portant le label dans les k µC les plus
+    proches, pondérée par l'inverse de la distance
+  - un label est prédit si sa probabilité atteint son seuil (Thresholds) ou le seuil commun (Threshold)
+*/
+
+// MultiLabel prédit un ensemble de labels pour chaque mesure
+type MultiLabel struct {
+	clusterer  *Clusterer
+	Radius     float64 // rayon des µC (0 : estimé lors du premier apprentissage)
+	threshold  int     // taille minimum des µC
+	zones      int
+	outlier    float64
+	Threshold  float64         // probabilité minimum pour prédire un label (0.5 par défaut)
+	Thresholds map[int]float64 // seuil propre à certains labels
+	Verbose    int
+
+	distFunction string // nom de la fonction distance (vide : fonction globale)
+}
+
+// NewMultiLabel crée un classifieur multi-label ; si radius vaut 0 il est estimé à partir des premières données apprises
+func NewMultiLabel(radius float64, threshold int, zones int, outlier float64) *MultiLabel {
+	return &MultiLabel{Radius: radius, threshold: threshold, zones: zones, outlier: outlier, Threshold: 0.5}
+}
+
+// SetDistanceFunction définit la fonction distance utilisée par les µC et par l'estimation du rayon, indépendamment de la
+// fonction distance globale
+func (m *MultiLabel) SetDistanceFunction(name string) error {
+	if _, exists := distanceFunctions[name]; !exists {
+		return fmt.Errorf("unknown distance function %q", name)
+	}
+	m.distFunction = name
+	if m.clusterer != nil {
+		return m.clusterer.SetDistanceFunction(name)
+	}
+	return nil
+}
+
+// FitXY réalise l'apprentissage des données 'X' dont les labels de chaque mesure sont précisés dans 'Y'
+func (m *MultiLabel) FitXY(X [][]float64, Y [][]int) error {
+	if len(X) != len(Y) {
+		return fmt.Errorf("data and label mismatch")
+	}
+	if len(X) == 0 {
+		return nil
+	}
+	if m.clusterer == nil {
+		if m.Radius == 0 && len(X) < 2 {
+			return fmt.Errorf("at least 2 points are required to estimate the radius")
+		}
+		clusterer := NewClusterer(m.Radius, m.threshold, m.zones, m.outlier)
+		if m.distFunction != "" {
+			if err := clusterer.SetDistanceFunction(m.distFunction); err != nil {
+				return err
+			}
+		}
+		if m.Radius == 0 {
+			mean, std := nnStats(X, clusterer.distance)
+			m.Radius = mean + 2*std
+			clusterer.mcRadius = m.Radius
+			if m.Verbose > 0 {
+				fmt.Println("=> radius=", m.Radius)
+			}
+		}
+		m.clusterer = clusterer
+	}
+
+	m.clusterer.mu.Lock()
+	defer m.clusterer.mu.Unlock()
+	m.clusterer.addPoints(X, nil, func(i int, mc *microcluster) {
+		if mc.Labels == nil {
+			mc.Labels = make(map[int]float64)
+		}
+		for _, label := range Y[i] {
+			mc.Labels[label]++
+		}
+	})
+	return nil
+}
+
+// PartialFit apprend une mesure 'x' portant les labels 'labels'. Le rayon doit être connu.
+func (m *MultiLabel) PartialFit(x []float64, labels []int) error {
+	if m.clusterer == nil && m.Radius == 0 {
+		return fmt.Errorf("radius must be set before incremental training")
+	}
+	return m.FitXY([][]float64{x}, [][]int{labels})
+}
+
+// Clusterer renvoie le Clusterer contenant les µC
+func (m *MultiLabel) Clusterer() *Clusterer {
+	return m.clusterer
+}
+
+// PredictProba renvoie la probabilité de chaque label connu des k µC les plus proches de 'x'
+func (m *MultiLabel) PredictProba(x []float64, k int) map[int]float64 {
+	proba := make(map[int]float64)
+	if m.clusterer == nil {
+		return proba
+	}
+	m.clusterer.mu.RLock()
+	defer m.clusterer.mu.RUnlock()
+	if len(m.clusterer.mc) == 0 {
+		return proba
+	}
+
+	type candidate struct {
+		distance float64
+		mc       *microcluster
+	}
+	candidates := make([]candidate, len(m.clusterer.mc))
+	for i, mc := range m.clusterer.mc {
+		candidates[i] = candidate{distance: m.clusterer.distance(x, mc.Center), mc: mc}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].distance < candidates[j].distance })
+	if k < len(candidates) {
+		candidates = candidates[:k]
+	}
+
+	total := 0.0
+	for _, cd := range candidates {
+		w := 1 / math.Max(cd.distance, 1e-12)
+		total += w
+		if cd.mc.Weight == 0 {
+			continue
+		}
+		for label, n := range cd.mc.Labels {
+			proba[label] += w * math.Min(n/float64(cd.mc.Weight), 1)
+		}
+	}
+	for label := range proba {
+		proba[label] /= total
+	}
+	return proba
+}
+
+// labelThreshold renvoie le seuil de prédiction du label
+func (m *MultiLabel) labelThreshold(label int) float64 {
+	if t, exists := m.Thresholds[label]; exists {
+		return t
+	}
+	return m.Threshold
+}
+
+// Predict renvoie les labels triés de chaque vecteur de 'x' dont la probabilité atteint le seuil
+func (m *MultiLabel) Predict(x [][]float64, k int) (y [][]int) {
+	for _, vector := range x {
+		labels := []int{}
+		for label, p := range m.PredictProba(vector, k) {
+			if p > 0 && p >= m.labelThreshold(label) {
+				labels = append(labels, label)
+			}
+		}
+		sort.Ints(labels)
+		y = append(y, labels)
+	}
+	return y
+}
+
+type multiLabelJSON struct {
+	Clusterer  *clustererJSON  `json:"clusterer,omitempty"` // µC et compteurs de labels
+	Radius     float64         `json:"radius"`
+	Threshold  int             `json:"threshold"`
+	Zones      int             `json:"zones"`
+	Outlier    float64         `json:"outlier"`
+	Proba      float64         `json:"proba_threshold"`
+	Thresholds map[int]float64 `json:"thresholds,omitempty"`
+	Verbose    int             `json:"verbose"`
+	Distance   string          `json:"distance,omitempty"` // fonction distance choisie par SetDistanceFunction
+}
+
+// ToJson exporte le classifieur multi-label en JSON
+func (m MultiLabel) ToJson() ([]byte, error) {
+	toExport := multiLabelJSON{
+		Radius:     m.Radius,
+		Threshold:  m.threshold,
+		Zones:      m.zones,
+		Outlier:    m.outlier,
+		Proba:      m.Threshold,
+		Thresholds: m.Thresholds,
+		Verbose:    m.Verbose,
+		Distance:   m.distFunction,
+	}
+	if m.clusterer != nil {
+		m.clusterer.mu.RLock()
+		defer m.clusterer.mu.RUnlock()
+		cl := m.clusterer.toJsonStruct()
+		toExport.Clusterer = &cl
+	}
+	return json.Marshal(toExport)
+}
+
+// NewMultiLabelFromJson crée un classifieur multi-label à partir d'un export JSON
+func NewMultiLabelFromJson(data []byte) (*MultiLabel, error) {
+	toImport := multiLabelJSON{}
+	if err := json.Unmarshal(data, &toImport); err != nil {
+		return nil, err
+	}
+
+	newMultiLabel := NewMultiLabel(toImport.Radius, toImport.Threshold, toImport.Zones, toImport.Outlier)
+	newMultiLabel.Threshold = toImport.Proba
+	newMultiLabel.Thresholds = toImport.Thresholds
+	newMultiLabel.Verbose = toImport.Verbose
+	newMultiLabel.distFunction = toImport.Distance
+	if toImport.Clusterer != nil {
+		d, err := json.Marshal(toImport.Clusterer)
+		if err != nil {
+			return nil, err
+		}
+		newMultiLabel.clusterer, err = NewClustererFromJson(d)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return newMultiLabel, nil
+}
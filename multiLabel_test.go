@@ -0,0 +1,110 @@
+package microClustering
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMultiLabel(t *testing.T) {
+	X, Y := blobs(100, [][]float64{{0, 0}, {5, 0}, {0, 5}}, 0.5)
+	labelSets := [][]int{{1}, {2}, {1, 2}}
+	Ys := make([][]int, len(Y))
+	for i, y := range Y {
+		Ys[i] = labelSets[y]
+	}
+
+	m := NewMultiLabel(1, 1, 1, 3)
+	if err := m.FitXY(X, Ys); err != nil {
+		t.Fatal(err)
+	}
+	pred := m.Predict([][]float64{{0, 0}, {5, 0}, {0, 5}}, 3)
+	for i, expected := range labelSets {
+		if fmt.Sprint(pred[i]) != fmt.Sprint(expected) {
+			t.Errorf("point %d: labels %v, expected %v", i, pred[i], expected)
+		}
+	}
+
+	// seuil propre à un label
+	m.Thresholds = map[int]float64{2: 1.1}
+	if got := m.Predict([][]float64{{0, 5}}, 3)[0]; fmt.Sprint(got) != "[1]" {
+		t.Errorf("label 2 should be filtered by its threshold, got %v", got)
+	}
+
+	data, err := m.ToJson()
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported, err := NewMultiLabelFromJson(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := imported.Predict([][]float64{{0, 5}}, 3)[0]; fmt.Sprint(got) != "[1]" {
+		t.Errorf("after import: labels %v, expected [1]", got)
+	}
+
+	// le rayon est estimé avec la fonction distance du classifieur
+	SetDistanceFunction("euclidian")
+	m = NewMultiLabel(0, 1, 1, 3)
+	if err := m.SetDistanceFunction("unknown"); err == nil {
+		t.Error("unknown distance function accepted")
+	}
+	if err := m.SetDistanceFunction("manhattan"); err != nil {
+		t.Fatal(err)
+	}
+	two := [][]float64{{0, 0}, {3, 4}} // distance au plus proche voisin : 7 (manhattan), 5 (euclidienne)
+	if err := m.FitXY(two, [][]int{{1}, {2}}); err != nil {
+		t.Fatal(err)
+	}
+	max, std := nnStats(two, ManhattanDistance)
+	if m.Radius != max+2*std || m.Clusterer().distFunction != "manhattan" {
+		t.Errorf("radius %f with distance %q, expected %f with manhattan", m.Radius, m.Clusterer().distFunction, max+2*std)
+	}
+}
+
+func TestHierarchical(t *testing.T) {
+	tree := NewLabelTree()
+	tree.AddEdge(1, 10)
+	tree.AddEdge(1, 11)
+	tree.AddEdge(2, 20)
+	tree.AddEdge(2, 21)
+	if err := tree.AddEdge(10, 1); err == nil {
+		t.Error("cycle should be rejected")
+	}
+	if err := tree.AddEdge(2, 10); err == nil {
+		t.Error("second parent should be rejected")
+	}
+
+	leaves := []int{10, 11, 20, 21}
+	X, Y := blobs(100, [][]float64{{0, 0}, {0, 3}, {10, 0}, {10, 3}}, 0.4)
+	for i := range Y {
+		Y[i] = leaves[Y[i]]
+	}
+	h := NewHierarchical(tree, 0.5, 1, 1, 3)
+	h.MinConfidence = 0.8
+	if err := h.FitXY(X, Y); err != nil {
+		t.Fatal(err)
+	}
+
+	pred := h.Predict([][]float64{{0, 0}, {0, 3}, {10, 0}, {10, 3}, {0, 1.5}}, 3)
+	expected := []int{10, 11, 20, 21, 1} // point entre 10 et 11 : repli sur le parent
+	for i := range expected {
+		if pred[i] != expected[i] {
+			t.Errorf("point %d: predicted %d, expected %d", i, pred[i], expected[i])
+		}
+	}
+
+	data, err := h.ToJson()
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported, err := NewHierarchicalFromJson(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := imported.Predict([][]float64{{10, 3}}, 3)[0]; got != 21 {
+		t.Errorf("after import: predicted %d, expected 21", got)
+	}
+	if p, _ := imported.Tree.Parent(21); p != 2 {
+		t.Errorf("tree not restored: parent of 21 is %d", p)
+	}
+}
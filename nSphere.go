@@ -6,7 +6,7 @@ import (
 )
 
 // unitySphere génère un point sur une sphere unitaire à N dimensions
-func unitySphere(n int) (point []float64) {
+func unitySphere(n int, rnd *rand.Rand) (point []float64) {
 	point = make([]float64, n)
 
 	euclidian := distanceName == "euclidian"
@@ -14,7 +14,7 @@ func unitySphere(n int) (point []float64) {
 	// génération X1 à Xn entre 0..1 (mean=0 et variance=1)
 	sum := 0.0
 	for i := range point {
-		point[i] = rand.Float64()
+		point[i] = rnd.Float64()
 		if euclidian {
 			sum += math.Pow(point[i], 2)
 		} else {
@@ -33,8 +33,8 @@ func unitySphere(n int) (point []float64) {
 	return point
 }
 
-func nSphere(center []float64, radius float64) (point []float64) {
-	unity := unitySphere(len(center))
+func nSphere(center []float64, radius float64, rnd *rand.Rand) (point []float64) {
+	unity := unitySphere(len(center), rnd)
 	point = make([]float64, len(center))
 	for i := range unity {
 		point[i] = center[i] + radius*unity[i]
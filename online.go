@@ -0,0 +1,115 @@
+package microClustering
+
+import (
+	"fmt"
+)
+
+/*
+  Apprentissage incrémental du classifieur
+
+  - Chaque mesure étiquetée est directement ajoutée au Clusterer de sa classe, sans tri du jeu de données.
+  - Si le rayon n'est pas connu (Radius=0 ou rayon de la classe inconnu avec PerClassRadius), les premières mesures
+    sont conservées dans un buffer de démarrage : lorsque le buffer est plein, le rayon est estimé comme dans Fit
+    puis les mesures du buffer sont apprises.
+  - L'oubli (RandomDelete) peut être appliqué régulièrement, avec des paramètres propres à chaque classe.
+  - Une classe qui ne reçoit plus de mesures peut être oubliée progressivement jusqu'à sa suppression (Fade).
+*/
+
+// defaultWarmUp est la taille par défaut du buffer de démarrage
+const defaultWarmUp = 100
+
+// Forgetting paramètre l'oubli appliqué à une classe lors de l'apprentissage incrémental
+type Forgetting struct {
+	Every int     `json:"every"` // applique l'oubli toutes les 'Every' mesures apprises par la classe (0 : pas d'oubli)
+	Pct   float64 `json:"pct"`   // pourcentage des mesures de la classe à supprimer
+	Proba float64 `json:"proba"` // probabilité de suppression de chaque mesure à chaque passage (1 si non précisée)
+
+	Fade int `json:"fade,omitempty"` // applique l'oubli toutes les 'Fade' mesures du flux tant que la classe n'en reçoit aucune (0 : pas d'oubli)
+}
+
+// PartialFit apprend une mesure 'x' de la classe 'y'.
+// Renvoie l'erreur de l'estimation du rayon lorsque le buffer de démarrage est plein : les mesures dont le rayon
+// reste inconnu sont conservées et l'estimation est retentée après 'WarmUp' nouvelles mesures.
+func (c *Classifier) PartialFit(x []float64, y int) error {
+	if !c.radiusKnown(y) { // rayon inconnu : la mesure est conservée dans le buffer de démarrage
+		c.warmUpData = append(c.warmUpData, append([]float64{}, x...))
+		c.warmUpLabels = append(c.warmUpLabels, y)
+		warmUp := c.WarmUp
+		if warmUp <= 0 {
+			warmUp = defaultWarmUp
+		}
+		if len(c.warmUpData)%warmUp == 0 {
+			return c.FlushWarmUp()
+		}
+		return nil
+	}
+	c.learn(x, y)
+	return nil
+}
+
+// PartialFitXY apprend les mesures 'X' dont les classes sont précisées dans 'Y'
+func (c *Classifier) PartialFitXY(X [][]float64, Y []int) error {
+	if len(X) != len(Y) {
+		return fmt.Errorf("data and label mismatch")
+	}
+	for i := range X {
+		if err := c.PartialFit(X[i], Y[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FlushWarmUp termine la phase de démarrage : estime les rayons inconnus à partir des mesures du buffer puis
+// apprend les mesures dont le rayon de la classe est connu. Les autres restent dans le buffer.
+// L'erreur de l'estimation est renvoyée après l'apprentissage des mesures dont le rayon est connu.
+func (c *Classifier) FlushWarmUp() error {
+	if len(c.warmUpData) == 0 {
+		return nil
+	}
+
+	// regroupe les mesures par classe
+	groups := make(map[int][][]float64)
+	for i, x := range c.warmUpData {
+		groups[c.warmUpLabels[i]] = append(groups[c.warmUpLabels[i]], x)
+	}
+	err := c.estimateRadii(groups) // les mesures des classes dont le rayon reste inconnu sont conservées dans le buffer
+
+	data, labels := c.warmUpData, c.warmUpLabels
+	c.warmUpData, c.warmUpLabels = nil, nil
+	for i := range data {
+		if c.radiusKnown(labels[i]) {
+			c.learn(data[i], labels[i])
+		} else {
+			c.warmUpData = append(c.warmUpData, data[i])
+			c.warmUpLabels = append(c.warmUpLabels, labels[i])
+		}
+	}
+	return err
+}
+
+// learn ajoute la mesure au Clusterer de sa classe et applique l'oubli de la classe si nécessaire
+func (c *Classifier) learn(x []float64, y int) {
+	cl := c.class(y)
+	cl.Add([][]float64{x})
+	c.tick++
+	c.seen(y)
+	c.fade()
+
+	f := c.forgetting(y)
+	if f.Every <= 0 || f.Pct <= 0 {
+		return
+	}
+	if c.learned == nil {
+		c.learned = make(map[int]int)
+	}
+	c.learned[y]++
+	if c.learned[y] >= f.Every {
+		c.learned[y] = 0
+		proba := f.Proba
+		if proba <= 0 {
+			proba = 1
+		}
+		cl.RandomDelete(f.Pct, proba)
+	}
+}
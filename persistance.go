@@ -2,6 +2,7 @@ package microClustering
 
 import(
   "encoding/json"
+  "sync/atomic"
 )
 
 type clustererJSON struct {
@@ -18,6 +19,20 @@ type clustererJSON struct {
 	VectorSize int `json:"vector_size"`
 	Distance   string   `json:"distance_function"` // fonction utilisée pour évaluer les distances
 	Mc         []microcluster `json:"mc_list"`// liste de tous les microclusters créés
+
+	ReservoirSize   int  `json:"reservoir_size,omitempty"`   // nombre maximum de mesures réelles conservées par µC
+	ExportExemplars bool `json:"export_exemplars,omitempty"` // les reservoirs sont exportés dans le snapshot
+
+	RadiusMode   RadiusMode `json:"radius_mode,omitempty"`   // mode de calcul du rayon de chaque µC
+	RadiusFactor float64    `json:"radius_factor,omitempty"` // facteur appliqué à l'écart quadratique moyen
+	MaxRadius    float64    `json:"max_radius,omitempty"`    // rayon maximum d'un µC
+
+	MaxMicroClusters int            `json:"max_micro_clusters,omitempty"` // nombre maximum de µC
+	CapacityPolicy   CapacityPolicy `json:"capacity_policy,omitempty"`    // politique appliquée lorsque le nombre maximum de µC est dépassé
+	Tick             int64          `json:"tick,omitempty"`               // nombre de mesures traitées
+
+	TrackFeatures bool           `json:"track_features,omitempty"` // les cluster features (LS, SS) sont maintenues
+	PrivacyBudget *PrivacyBudget `json:"privacy_budget,omitempty"` // budget de confidentialité différentielle consommé
 }
 
 type classifierJSON struct {
@@ -28,29 +43,36 @@ type classifierJSON struct {
 	LabelID       int `json:"label_id"`
 	Verbose       int`json:"verbose"`
 	Zones         int `json:"zones"`
-	CheckOutliers bool `json:"check_outliers"`// TODO: si un point est un outlier pour l'ensemble des classe alors renvoie une classe -1
-}
+	CheckOutliers bool `json:"check_outliers"`// si un point est un outlier pour l'ensemble des classes alors renvoie la classe UnknownLabel
 
+	PerClassRadius   bool             `json:"per_class_radius,omitempty"`  // un rayon est estimé pour chaque classe
+	ClassRadius      map[int]float64  `json:"class_radius,omitempty"`      // rayon de chaque classe
+	RadiusEstimation RadiusEstimation `json:"radius_estimation,omitempty"` // stratégie d'estimation du rayon
+	RadiusQuantile   float64          `json:"radius_quantile,omitempty"`   // quantile des distances au plus proche voisin
+	TargetMC         int              `json:"target_mc,omitempty"`         // nombre de µC visé par classe
 
-// Export Clusterer to Json
-func (c Clusterer)ToJson() ([]byte,error) {
-  toExport:=clustererJSON{
-McRadius: c.mcRadius,
-MinSize:c.minSize,
-Zones:c.zones,
-MediumSize:c.mediumSize,
-SigmaSize:c.sigmaSize,
-OutlierThreshold:c.outlierThreshold,
-VectorSize:c.vectorSize,
-Distance: c.distFunction,
-  }
+	Decision        DecisionRule       `json:"decision,omitempty"`         // règle de décision
+	Weighting       Weighting          `json:"weighting,omitempty"`        // pondération du vote des µC voisins
+	UnknownLabel    int                `json:"unknown_label"`              // classe renvoyée pour les prédictions rejetées
+	RejectMargin    float64            `json:"reject_margin,omitempty"`    // écart de probabilité minimum entre les deux classes les plus probables
+	Rejected        int64              `json:"rejected,omitempty"`         // nombre de prédictions rejetées
+	Distance        string             `json:"distance_function,omitempty"` // fonction distance utilisée par les classes
+	Labels          []string           `json:"labels,omitempty"`           // libellés textuels des classes, l'indice est le code de la classe
+	WarmUp          int                `json:"warm_up,omitempty"`          // taille du buffer de démarrage
+	Forgetting      Forgetting         `json:"forgetting"`                 // oubli appliqué par défaut à chaque classe
+	ClassForgetting map[int]Forgetting `json:"class_forgetting,omitempty"` // oubli propre à certaines classes
+	WarmUpData      [][]float64        `json:"warm_up_data,omitempty"`     // buffer de démarrage
+	WarmUpLabels    []int              `json:"warm_up_labels,omitempty"`   // classes des mesures du buffer de démarrage
+	Tick            int64              `json:"tick,omitempty"`             // nombre de mesures apprises par PartialFit
+	LastSeen        map[int]int64      `json:"last_seen,omitempty"`        // dernière mesure apprise par chaque classe
+}
 
 
-  toExport.Mc=[]microcluster{}
-  for _,v:=range c.mc {
-    toExport.Mc=append(toExport.Mc,*v)
-  }
-  return json.Marshal(toExport)
+// Export Clusterer to Json
+func (c *Clusterer)ToJson() ([]byte,error) {
+  c.mu.RLock()
+  defer c.mu.RUnlock()
+  return json.Marshal(c.toJsonStruct())
 }
 
 
@@ -72,6 +94,22 @@ newClusterer:=Clusterer{
   outlierThreshold:toImport.OutlierThreshold ,
   vectorSize:toImport.VectorSize ,
   distFunction :toImport.Distance,
+  distance: distanceFunctions[toImport.Distance],
+  ReservoirSize: toImport.ReservoirSize,
+  ExportExemplars: toImport.ExportExemplars,
+  TrackFeatures: toImport.TrackFeatures,
+  RadiusMode: toImport.RadiusMode,
+  RadiusFactor: toImport.RadiusFactor,
+  MaxRadius: toImport.MaxRadius,
+  MaxMicroClusters: toImport.MaxMicroClusters,
+  CapacityPolicy: toImport.CapacityPolicy,
+  tick: toImport.Tick,
+}
+if toImport.PrivacyBudget!=nil {
+  newClusterer.PrivacyBudget=*toImport.PrivacyBudget
+}
+if newClusterer.distance==nil {
+  newClusterer.distance=Distance
 }
 
   newClusterer.mc=[]*microcluster{}
@@ -80,6 +118,14 @@ newClusterer:=Clusterer{
     mc:=microcluster{
       Weight:v.Weight,
     Zones: v.Zones,
+    Exemplars: v.Exemplars,
+    Seen: v.Seen,
+    Radius: v.Radius,
+    LastUpdate: v.LastUpdate,
+    Target: v.Target,
+    Labels: v.Labels,
+    LS: v.LS,
+    SS: v.SS,
     }
     mc.Center= make([]float64,len(v.Center))
     copy(mc.Center,v.Center)
@@ -91,7 +137,7 @@ newClusterer:=Clusterer{
 // NewClassifierFromJson creates a new classifier from to Json export
 func NewClassifierFromJson(data []byte) (*Classifier, error) {
 
-  toImport:=classifierJSON{}
+  toImport:=classifierJSON{UnknownLabel: -1}
   err:=json.Unmarshal(data, &toImport)
 if err!=nil{
   return nil, err
@@ -105,9 +151,30 @@ newClassifier:=Classifier{
   threshold:toImport.Threshold,
   Verbose:toImport.Verbose,
   zones: toImport.Zones,
+  PerClassRadius: toImport.PerClassRadius,
+  ClassRadius: toImport.ClassRadius,
+  RadiusEstimation: toImport.RadiusEstimation,
+  RadiusQuantile: toImport.RadiusQuantile,
+  TargetMC: toImport.TargetMC,
+  Decision: toImport.Decision,
+  Weighting: toImport.Weighting,
+  distFunction: toImport.Distance,
+  UnknownLabel: toImport.UnknownLabel,
+  RejectMargin: toImport.RejectMargin,
+  rejected: toImport.Rejected,
+  WarmUp: toImport.WarmUp,
+  Forgetting: toImport.Forgetting,
+  ClassForgetting: toImport.ClassForgetting,
+  warmUpData: toImport.WarmUpData,
+  warmUpLabels: toImport.WarmUpLabels,
+  tick: toImport.Tick,
+  lastSeen: toImport.LastSeen,
 }
 
 newClassifier.classes=make(map[int]*Clusterer)
+if toImport.Labels!=nil {
+  newClassifier.Labels=NewLabelEncoder(toImport.Labels...)
+}
 
   for k,v:=range toImport.Classes {
     d,err:=json.Marshal(v)
@@ -126,7 +193,7 @@ newClassifier.classes=make(map[int]*Clusterer)
 }
 
 
-func (c Clusterer)toJsonStruct() clustererJSON {
+func (c *Clusterer)toJsonStruct() clustererJSON {
   toExport:=clustererJSON{
 McRadius: c.mcRadius,
 MinSize:c.minSize,
@@ -136,18 +203,37 @@ SigmaSize:c.sigmaSize,
 OutlierThreshold:c.outlierThreshold,
 VectorSize:c.vectorSize,
 Distance: c.distFunction,
+ReservoirSize: c.ReservoirSize,
+ExportExemplars: c.ExportExemplars,
+TrackFeatures: c.TrackFeatures,
+RadiusMode: c.RadiusMode,
+RadiusFactor: c.RadiusFactor,
+MaxRadius: c.MaxRadius,
+MaxMicroClusters: c.MaxMicroClusters,
+CapacityPolicy: c.CapacityPolicy,
+Tick: c.tick,
+  }
+  if c.PrivacyBudget!=(PrivacyBudget{}) {
+    budget:=c.PrivacyBudget
+    toExport.PrivacyBudget=&budget
   }
 
 
   toExport.Mc=[]microcluster{}
   for _,v:=range c.mc {
-    toExport.Mc=append(toExport.Mc,*v)
+    mc:=*v
+    if !c.ExportExemplars { // les reservoirs ne sont exportés qu'à la demande
+      mc.Exemplars=nil
+      mc.Seen=0
+    }
+    toExport.Mc=append(toExport.Mc,mc)
   }
   return toExport
 }
 
 // Export Classifier to Json
-func (c Classifier)ToJson() ([]byte,error) {
+// Le receveur est un pointeur : le compteur de rejets, modifié par les prédictions concurrentes, est lu par sync/atomic
+func (c *Classifier)ToJson() ([]byte,error) {
   toExport:=classifierJSON{
     CheckOutliers:c.CheckOutliers,
     Radius:c.Radius,
@@ -156,11 +242,35 @@ func (c Classifier)ToJson() ([]byte,error) {
     Outlier:c.outlier,
     Threshold:c.threshold,
     Zones:c.zones,
+    PerClassRadius:c.PerClassRadius,
+    ClassRadius:c.ClassRadius,
+    RadiusEstimation:c.RadiusEstimation,
+    RadiusQuantile:c.RadiusQuantile,
+    TargetMC:c.TargetMC,
+    Decision:c.Decision,
+    Weighting:c.Weighting,
+    Distance:c.distFunction,
+    UnknownLabel:c.UnknownLabel,
+    RejectMargin:c.RejectMargin,
+    Rejected:atomic.LoadInt64(&c.rejected),
+    WarmUp:c.WarmUp,
+    Forgetting:c.Forgetting,
+    ClassForgetting:c.ClassForgetting,
+    WarmUpData:c.warmUpData,
+    WarmUpLabels:c.warmUpLabels,
+    Tick:c.tick,
+    LastSeen:c.lastSeen,
+  }
+
+  if c.Labels!=nil {
+    toExport.Labels=c.Labels.Labels()
   }
 
   toExport.Classes=make(map[int]clustererJSON)
   for k,cl:=range c.classes {
-    toExport.Classes[k]=(*cl).toJsonStruct()
+    cl.mu.RLock() // les µC exportés sont lus jusqu'à la fin de l'export
+    defer cl.mu.RUnlock()
+    toExport.Classes[k]=cl.toJsonStruct()
   }
 
   return json.Marshal(toExport)
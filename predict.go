@@ -0,0 +1,204 @@
+package microClustering
+
+import (
+	"math"
+	"sort"
+	"sync/atomic"
+)
+
+// Weighting définit la pondération du vote des µC voisins
+type Weighting int
+
+const (
+	// UniformWeighting : chaque µC voisin compte pour une voix
+	UniformWeighting Weighting = iota
+	// InverseDistanceWeighting : la voix d'un µC est inversement proportionnelle à sa distance
+	InverseDistanceWeighting
+	// MCWeightWeighting : la voix d'un µC est proportionnelle à son poids
+	MCWeightWeighting
+)
+
+// labels renvoie la liste triée des libellés des classes
+func (c *Classifier) labels() (labels []int) {
+	for label := range c.classes {
+		labels = append(labels, label)
+	}
+	sort.Ints(labels)
+	return labels
+}
+
+// neighbors renvoie les µC les plus proches de 'x' toutes classes confondues, jusqu'à la k-ième distance
+// (plusieurs µC peuvent être à la même distance). L'ordre est déterministe.
+func (c *Classifier) neighbors(x []float64, k int) neighborList {
+	nearestNeighbors := neighborList{} // liste des µC les plus proches
+	// recherche les k NN de chaque classe
+	for _, key := range c.labels() {
+		cl := c.classes[key]
+		cl.mu.RLock()
+		nb := cl.knn(x, k, c.Decision != PlainKNNDecision)
+		cl.mu.RUnlock()
+		for i := range nb {
+			nb[i].class = key
+			nearestNeighbors = append(nearestNeighbors, nb[i])
+		}
+	}
+
+	//trie par distance
+	sort.Stable(nearestNeighbors)
+
+	// recherche la k ieme distance (plusieurs points peuvent être à la même distance)
+	toK := 0
+	lastValue := -1.0
+	for i := range nearestNeighbors {
+		if nearestNeighbors[i].distance != lastValue {
+			lastValue = nearestNeighbors[i].distance
+			toK++
+		}
+		if toK == k {
+			// inclut les µC suivants situés à la même distance
+			j := i + 1
+			for j < len(nearestNeighbors) && nearestNeighbors[j].distance == lastValue {
+				j++
+			}
+			return nearestNeighbors[:j]
+		}
+	}
+	return nearestNeighbors
+}
+
+// vote calcule la part des voix de chaque classe parmi les µC voisins, selon la pondération du classifieur.
+// Toutes les classes connues sont présentes dans le résultat.
+func (c *Classifier) vote(nearestNeighbors neighborList) map[int]float64 {
+	proba := make(map[int]float64)
+	for key := range c.classes {
+		proba[key] = 0
+	}
+
+	total := 0.0
+	for _, v := range nearestNeighbors {
+		w := 1.0
+		switch c.Weighting {
+		case InverseDistanceWeighting:
+			w = 1 / math.Max(v.distance, 1e-12)
+		case MCWeightWeighting:
+			w = float64(v.weight)
+		}
+		proba[v.class] += w
+		total += w
+	}
+	if total > 0 {
+		for key := range proba {
+			proba[key] /= total
+		}
+	}
+	return proba
+}
+
+// bestClass renvoie la classe ayant la plus forte probabilité.
+// En cas d'égalité, la classe du µC voisin le plus proche l'emporte puis le plus petit libellé.
+func bestClass(proba map[int]float64, nearestNeighbors neighborList) int {
+	best := 0
+	bestProba := -1.0
+	bestRank := math.MaxInt64
+	for key, p := range proba {
+		rank := math.MaxInt64
+		for i, v := range nearestNeighbors {
+			if v.class == key {
+				rank = i
+				break
+			}
+		}
+		if p > bestProba || p == bestProba && (rank < bestRank || rank == bestRank && key < best) {
+			best = key
+			bestProba = p
+			bestRank = rank
+		}
+	}
+	return best
+}
+
+// predict renvoie la probabilité de chaque classe pour le vecteur 'x' selon la règle de décision du classifieur,
+// ainsi que les µC voisins utilisés pour départager les égalités (règles kNN uniquement)
+func (c *Classifier) predict(x []float64, k int) (map[int]float64, neighborList) {
+	switch c.Decision {
+	case CentroidDecision:
+		return c.centroidProba(x), nil
+	case ParzenDecision:
+		return c.parzenProba(x), nil
+	}
+	nearestNeighbors := c.neighbors(x, k)
+	return c.vote(nearestNeighbors), nearestNeighbors
+}
+
+// PredictProba renvoie la probabilité de chaque classe pour le vecteur 'x', estimée selon la règle de décision du
+// classifieur (par défaut le vote des k µC les plus proches)
+func (c *Classifier) PredictProba(x []float64, k int) map[int]float64 {
+	proba, _ := c.predict(x, k)
+	return proba
+}
+
+// PredictProbaMatrix renvoie les probabilités des classes pour chaque vecteur de 'x' sous forme de matrice :
+// proba[i][j] est la probabilité de la classe classes[j] pour le vecteur x[i], les classes étant triées
+func (c *Classifier) PredictProbaMatrix(x [][]float64, k int) (classes []int, proba [][]float64) {
+	classes = c.labels()
+	for _, vector := range x {
+		p := c.PredictProba(vector, k)
+		row := make([]float64, len(classes))
+		for j, label := range classes {
+			row[j] = p[label]
+		}
+		proba = append(proba, row)
+	}
+	return classes, proba
+}
+
+// IsOutlier renvoie true si le vecteur 'x' est un outlier pour chacune des classes
+func (c *Classifier) IsOutlier(x []float64) bool {
+	for _, cl := range c.classes {
+		if !cl.IsOutlier(x) {
+			return false
+		}
+	}
+	return true
+}
+
+// OutlierScore renvoie le plus petit score d'outlier du vecteur 'x' parmi les classes (voir Clusterer.OutlierScore)
+func (c *Classifier) OutlierScore(x []float64) float64 {
+	score := math.Inf(1)
+	for _, cl := range c.classes {
+		score = math.Min(score, cl.OutlierScore(x))
+	}
+	return score
+}
+
+// margin renvoie l'écart entre les deux plus fortes probabilités
+func margin(proba map[int]float64) float64 {
+	first, second := 0.0, 0.0
+	for _, p := range proba {
+		if p > first {
+			first, second = p, first
+		} else if p > second {
+			second = p
+		}
+	}
+	return first - second
+}
+
+// reject indique si la prédiction du vecteur 'x' doit être rejetée :
+// le vecteur est un outlier pour toutes les classes (CheckOutliers) ou la prédiction n'est pas assez franche (RejectMargin)
+func (c *Classifier) reject(x []float64, proba map[int]float64) bool {
+	if c.CheckOutliers && c.IsOutlier(x) {
+		return true
+	}
+	return c.RejectMargin > 0 && margin(proba) < c.RejectMargin
+}
+
+// Rejected renvoie le nombre de prédictions rejetées depuis la création du classifieur ou le dernier appel à ResetRejected
+func (c *Classifier) Rejected() int {
+	return int(atomic.LoadInt64(&c.rejected))
+}
+
+// ResetRejected remet à zéro le compteur de prédictions rejetées
+func (c *Classifier) ResetRejected() {
+	atomic.StoreInt64(&c.rejected, 0)
+}
@@ -0,0 +1,196 @@
+package microClustering
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+/*
+  Export différentiellement privé des µC
+
+  - Chaque mesure appartient à un seul µC : les statistiques publiées pour des µC différents portent sur des
+    mesures disjointes (composition parallèle), le bruit est donc calibré sur la contribution d'une seule mesure.
+  - Statistiques bruitées :
+      - Zones (le poids publié est la somme des zones bruitées, sans coût supplémentaire)
+      - LS et SS si les cluster features sont maintenues, sinon la somme des mesures (centre × poids)
+      - le centre publié est la somme bruitée divisée par le poids bruité : aucune sensibilité ne dépend du poids réel
+  - Les µC dont le poids bruité est inférieur au seuil de k-anonymat sont supprimés.
+  - Les statistiques de poids (moyenne, écart-type) sont recalculées à partir des poids bruités.
+  - Chaque publication consomme (ε, δ) du budget du Clusterer (composition séquentielle).
+  - Les sensibilités reposent sur 'Bound' : chaque composante est ramenée dans [-Bound, Bound] avant sommation
+    (le centre, ou LS et SS bornés par Bound × poids), une mesure ne peut donc pas contribuer au-delà de Bound.
+  - Le mécanisme gaussien (δ>0) utilise la calibration classique σ = Δ2·sqrt(2 ln(1.25/δ))/ε, valide pour ε<1 et
+    0<δ<1 : ces conditions sont vérifiées avant toute publication.
+  - Limite : la structure des µC (création, fusion et suppression des µC, affectation des mesures) dépend des données
+    et n'est pas bruitée. Une mesure peut donc modifier le nombre de µC publiés : même avec le mécanisme de Laplace
+    (δ=0), l'export n'est pas strictement ε-différentiellement privé, la garantie porte sur les statistiques publiées
+    pour une structure de µC donnée.
+*/
+
+// PrivacyParams paramètre un export différentiellement privé
+type PrivacyParams struct {
+	Epsilon   float64 // budget ε consommé par la publication (inférieur à 1 pour le mécanisme gaussien)
+	Delta     float64 // δ consommé par la publication : 0 pour le mécanisme de Laplace, sinon mécanisme gaussien (0<δ<1)
+	MinWeight int     // seuil de k-anonymat : les µC de poids bruité inférieur sont supprimés
+	Bound     float64 // borne sur la valeur absolue de chaque composante des mesures
+}
+
+// PrivacyBudget comptabilise le budget de confidentialité consommé par les publications successives
+type PrivacyBudget struct {
+	MaxEpsilon float64 `json:"max_epsilon,omitempty"` // budget ε total autorisé (0 : illimité)
+	MaxDelta   float64 `json:"max_delta,omitempty"`   // budget δ total autorisé (0 : illimité)
+	Epsilon    float64 `json:"epsilon"`               // ε consommé
+	Delta      float64 `json:"delta"`                 // δ consommé
+	Releases   int     `json:"releases"`              // nombre de publications
+}
+
+// spend consomme le budget d'une publication, renvoie une erreur si le budget total est dépassé
+func (b *PrivacyBudget) spend(epsilon, delta float64) error {
+	if b.MaxEpsilon > 0 && b.Epsilon+epsilon > b.MaxEpsilon {
+		return fmt.Errorf("privacy budget exhausted : epsilon %g + %g > %g", b.Epsilon, epsilon, b.MaxEpsilon)
+	}
+	if b.MaxDelta > 0 && b.Delta+delta > b.MaxDelta {
+		return fmt.Errorf("privacy budget exhausted : delta %g + %g > %g", b.Delta, delta, b.MaxDelta)
+	}
+	b.Epsilon += epsilon
+	b.Delta += delta
+	b.Releases++
+	return nil
+}
+
+// noise renvoie un bruit de Laplace (delta=0) ou gaussien calibré pour une sensibilité L1 'l1' ou L2 'l2'.
+// La calibration gaussienne suppose epsilon<1 et 0<delta<1 (voir PrivacyParams.validate)
+func noise(epsilon, delta, l1, l2 float64) float64 {
+	if delta > 0 {
+		sigma := l2 * math.Sqrt(2*math.Log(1.25/delta)) / epsilon
+		return rand.NormFloat64() * sigma
+	}
+	b := l1 / epsilon
+	u := rand.Float64() - 0.5
+	for u == -0.5 {
+		u = rand.Float64() - 0.5
+	}
+	if u < 0 {
+		return b * math.Log(1+2*u)
+	}
+	return -b * math.Log(1-2*u)
+}
+
+// validate vérifie les paramètres de la publication
+func (p PrivacyParams) validate() error {
+	if p.Epsilon <= 0 {
+		return fmt.Errorf("epsilon must be positive")
+	}
+	if p.Delta < 0 || p.Delta >= 1 {
+		return fmt.Errorf("delta must be in [0, 1)")
+	}
+	if p.Delta > 0 && p.Epsilon >= 1 {
+		return fmt.Errorf("the gaussian mechanism requires epsilon < 1")
+	}
+	if p.Bound <= 0 {
+		return fmt.Errorf("bound must be positive")
+	}
+	return nil
+}
+
+// privateCopy crée une copie bruitée du Clusterer et consomme le budget correspondant
+func (c *Clusterer) privateCopy(p PrivacyParams) (*Clusterer, error) {
+	c.mu.Lock() // le budget est modifié
+	defer c.mu.Unlock()
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	if err := c.PrivacyBudget.spend(p.Epsilon, p.Delta); err != nil {
+		return nil, err
+	}
+
+	minWeight := p.MinWeight
+	if minWeight < 1 {
+		minWeight = 1
+	}
+
+	// le budget est réparti entre les statistiques publiées
+	parts := 2.0 // zones + centre
+	if c.TrackFeatures {
+		parts = 3 // zones + LS + SS
+	}
+	epsilon := p.Epsilon / parts
+	delta := p.Delta / parts
+	dims := float64(c.vectorSize)
+
+	private := NewClusterer(c.mcRadius, c.minSize, c.zones, c.outlierThreshold)
+	private.distFunction = c.distFunction
+	private.distance = c.distance
+	private.vectorSize = c.vectorSize
+	private.TrackFeatures = c.TrackFeatures
+	private.RadiusMode = c.RadiusMode
+	private.RadiusFactor = c.RadiusFactor
+	private.MaxRadius = c.MaxRadius
+
+	for _, mc := range c.mc {
+		newMc := microcluster{Zones: make([]int, len(mc.Zones))}
+		for z := range mc.Zones {
+			// une mesure modifie une seule zone d'une unité
+			newMc.Zones[z] = int(math.Max(0, math.Round(float64(mc.Zones[z])+noise(epsilon, delta, 1, 1))))
+			newMc.Weight += newMc.Zones[z]
+		}
+		if newMc.Weight < minWeight { // k-anonymat
+			continue
+		}
+
+		newMc.Center = make([]float64, len(mc.Center))
+		weight := float64(mc.Weight)
+		if c.TrackFeatures && mc.LS != nil {
+			newMc.LS = make([]float64, len(mc.LS))
+			newMc.SS = make([]float64, len(mc.SS))
+			for i := range mc.LS {
+				// une mesure modifie LS d'au plus Bound et SS d'au plus Bound² sur chaque dimension
+				ls := clip(mc.LS[i], -p.Bound*weight, p.Bound*weight)
+				ss := clip(mc.SS[i], 0, p.Bound*p.Bound*weight)
+				newMc.LS[i] = ls + noise(epsilon, delta, dims*p.Bound, math.Sqrt(dims)*p.Bound)
+				newMc.SS[i] = math.Max(0, ss+noise(epsilon, delta, dims*p.Bound*p.Bound, math.Sqrt(dims)*p.Bound*p.Bound))
+				newMc.Center[i] = newMc.LS[i] / float64(newMc.Weight)
+			}
+		} else {
+			// la somme des mesures (centre × poids) varie d'au plus Bound par dimension lorsqu'une mesure est ajoutée ou retirée
+			for i := range mc.Center {
+				sum := clip(mc.Center[i], -p.Bound, p.Bound) * weight
+				newMc.Center[i] = (sum + noise(epsilon, delta, dims*p.Bound, math.Sqrt(dims)*p.Bound)) / float64(newMc.Weight)
+			}
+		}
+		private.updateRadius(&newMc)
+		private.mc = append(private.mc, &newMc)
+	}
+	private.updateStats() // les statistiques de poids réelles ne sont pas publiées
+	private.PrivacyBudget = c.PrivacyBudget
+	return private, nil
+}
+
+// clip ramène 'v' dans l'intervalle [min, max]
+func clip(v, min, max float64) float64 {
+	return math.Max(min, math.Min(max, v))
+}
+
+// PrivateToJson exporte en JSON une version différentiellement privée du Clusterer.
+// Les reservoirs d'exemples ne sont jamais exportés et le budget consommé est comptabilisé dans PrivacyBudget
+func (c *Clusterer) PrivateToJson(p PrivacyParams) ([]byte, error) {
+	private, err := c.privateCopy(p)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(private.toJsonStruct())
+}
+
+// PrivateGenerate génère un jeu de données de 'size' éléments à partir d'une version différentiellement privée des µC
+func (c *Clusterer) PrivateGenerate(size int, p PrivacyParams) ([][]float64, error) {
+	private, err := c.privateCopy(p)
+	if err != nil {
+		return nil, err
+	}
+	if private.Size() == 0 {
+		return nil, fmt.Errorf("no micro-cluster left after k-anonymity suppression")
+	}
+	return private.Generate(size), nil
+}
@@ -0,0 +1,102 @@
+package microClustering
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPrivateExport(t *testing.T) {
+
+	data := [][]float64{}
+	for i := 0; i < 200; i++ {
+		data = append(data, []float64{float64(i%2) * 5, float64(i%2) * 5})
+	}
+
+	SetDistanceFunction("euclidian")
+	c := NewClusterer(1.0, 1, 2, 2)
+	c.TrackFeatures = true
+	c.PrivacyBudget.MaxEpsilon = 1.5
+	c.Add(data)
+
+	p := PrivacyParams{Epsilon: 1, MinWeight: 10, Bound: 10}
+	js, err := c.PrivateToJson(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println("js:", string(js))
+
+	private, err := NewClustererFromJson(js)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if private.CountMC() != 2 {
+		t.Errorf("private export : %d µC, expected 2", private.CountMC())
+	}
+	if c.PrivacyBudget.Epsilon != 1 || c.PrivacyBudget.Releases != 1 {
+		t.Errorf("budget not tracked : %+v", c.PrivacyBudget)
+	}
+
+	// le budget restant ne permet pas une seconde publication
+	if _, err := c.PrivateGenerate(100, p); err == nil {
+		t.Error("privacy budget overrun not detected")
+	}
+
+	p.Epsilon = 0.5
+	p.Delta = 1e-5
+	generated, err := c.PrivateGenerate(100, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(generated) < 100 {
+		t.Errorf("generated %d points", len(generated))
+	}
+
+	// paramètres hors du domaine de validité des mécanismes : aucun budget consommé
+	c = NewClusterer(1, 1, 1, 2)
+	c.Add([][]float64{{0, 0}})
+	for _, invalid := range []PrivacyParams{
+		{Epsilon: 0, Bound: 10},
+		{Epsilon: 0.5, Delta: -1e-5, Bound: 10},
+		{Epsilon: 0.5, Delta: 1, Bound: 10},
+		{Epsilon: 1, Delta: 1e-5, Bound: 10},
+		{Epsilon: 0.5},
+	} {
+		if _, err := c.PrivateToJson(invalid); err == nil {
+			t.Errorf("invalid parameters accepted : %+v", invalid)
+		}
+	}
+	if c.PrivacyBudget != (PrivacyBudget{}) {
+		t.Errorf("budget consumed by rejected releases : %+v", c.PrivacyBudget)
+	}
+}
+
+func TestPrivateClipping(t *testing.T) {
+	c := NewClusterer(1.0, 1, 1, 2)
+	c.SetDistanceFunction("euclidian")
+	for i := 0; i < 100; i++ {
+		c.Add([][]float64{{50, 0}, {0, 0}, {0, 0}})
+		if i < 5 {
+			c.Add([][]float64{{0, 5}})
+		}
+	}
+
+	// bruit négligeable : seul le bornage des composantes modifie le centre publié, le µC de poids 5 est supprimé
+	private, err := c.privateCopy(PrivacyParams{Epsilon: 1e9, MinWeight: 10, Bound: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mc := range private.mc {
+		if mc.Center[0] > 10+1e-6 {
+			t.Errorf("center %v exceeds the bound", mc.Center)
+		}
+	}
+
+	// les statistiques de poids sont celles des µC publiés
+	if private.CountMC() != 2 {
+		t.Fatalf("%d µC published, expected 2", private.CountMC())
+	}
+	mean := float64(private.mc[0].Weight+private.mc[1].Weight) / 2
+	if private.mediumSize != mean {
+		t.Errorf("medium size %v, expected the mean of the noised weights %v", private.mediumSize, mean)
+	}
+}
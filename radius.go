@@ -0,0 +1,66 @@
+package microClustering
+
+import (
+	"math"
+)
+
+// RadiusMode définit le calcul du rayon de chaque µC
+type RadiusMode int
+
+const (
+	// FixedRadius : tous les µC ont le rayon global mcRadius
+	FixedRadius RadiusMode = iota
+	// WeightedRadius : le rayon croît avec le poids du µC, mcRadius*(1+log(weight))
+	WeightedRadius
+	// DeviationRadius : le rayon est RadiusFactor fois l'écart quadratique moyen des mesures du µC (comme CluStream),
+	// sans descendre sous mcRadius. Nécessite les cluster features, activées automatiquement
+	DeviationRadius
+)
+
+// radiusOf renvoie le rayon à utiliser pour le µC
+func (c *Clusterer) radiusOf(mc *microcluster) float64 {
+	if mc.Radius > 0 {
+		return mc.Radius
+	}
+	return c.mcRadius
+}
+
+// updateRadius recalcule le rayon propre du µC après une modification de son contenu
+func (c *Clusterer) updateRadius(mc *microcluster) {
+	radius := 0.0
+
+	switch c.RadiusMode {
+	case WeightedRadius:
+		if mc.Weight > 0 {
+			radius = c.mcRadius * (1 + math.Log(float64(mc.Weight)))
+		}
+	case DeviationRadius:
+		factor := c.RadiusFactor
+		if factor == 0 {
+			factor = 2
+		}
+		radius = math.Max(c.mcRadius, factor*mc.deviation())
+	}
+
+	if c.MaxRadius > 0 {
+		if radius == 0 {
+			radius = c.mcRadius
+		}
+		radius = math.Min(radius, c.MaxRadius)
+	}
+	mc.Radius = radius
+}
+
+// deviation renvoie l'écart quadratique moyen des mesures du µC par rapport à son centre (0 sans cluster features)
+func (mc *microcluster) deviation() float64 {
+	if mc.LS == nil || mc.Weight < 2 {
+		return 0
+	}
+	w := float64(mc.Weight)
+	variance := 0.0
+	for i := range mc.LS {
+		mean := mc.LS[i] / w
+		variance += mc.SS[i]/w - mean*mean
+	}
+	return math.Sqrt(math.Max(0, variance))
+}
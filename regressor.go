@@ -0,0 +1,212 @@
+package microClustering
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+/*
+  Régression kNN sur les µC
+
+  - les mesures sont regroupées en µC par un Clusterer unique
+  - chaque µC maintient la moyenne et la variance de la variable cible des mesures qu'il a absorbées
+  - la prédiction est la moyenne des cibles des k µC les plus proches, pondérée par l'inverse de la distance
+  - l'intervalle de prédiction est calculé à partir de la variance du mélange des k µC
+*/
+
+// targetStats maintient le nombre, la moyenne et la somme des carrés des écarts à la moyenne (algorithme de Welford)
+type targetStats struct {
+	Count int     `json:"count"`
+	Mean  float64 `json:"mean"`
+	M2    float64 `json:"m2"`
+}
+
+// add ajoute une valeur de la variable cible
+func (t *targetStats) add(y float64) {
+	t.Count++
+	delta := y - t.Mean
+	t.Mean += delta / float64(t.Count)
+	t.M2 += delta * (y - t.Mean)
+}
+
+// merge fusionne les statistiques 'other' (algorithme parallèle de Chan)
+func (t *targetStats) merge(other targetStats) {
+	if other.Count == 0 {
+		return
+	}
+	count := t.Count + other.Count
+	delta := other.Mean - t.Mean
+	t.M2 += other.M2 + delta*delta*float64(t.Count)*float64(other.Count)/float64(count)
+	t.Mean += delta * float64(other.Count) / float64(count)
+	t.Count = count
+}
+
+// variance renvoie la variance de la variable cible
+func (t targetStats) variance() float64 {
+	if t.Count < 2 {
+		return 0
+	}
+	return t.M2 / float64(t.Count)
+}
+
+// Regressor prédit une variable continue par kNN sur des µC
+type Regressor struct {
+	clusterer *Clusterer
+	Radius    float64 // rayon des µC (0 : estimé lors du premier apprentissage)
+	threshold int     // taille minimum des µC
+	zones     int
+	outlier   float64
+	Verbose   int
+}
+
+// NewRegressor crée un Regressor ; si radius vaut 0 il est estimé à partir des premières données apprises
+func NewRegressor(radius float64, threshold int, zones int, outlier float64) *Regressor {
+	return &Regressor{Radius: radius, threshold: threshold, zones: zones, outlier: outlier}
+}
+
+// FitXY réalise l'apprentissage des données 'X' dont la variable cible est précisée dans 'Y'
+func (r *Regressor) FitXY(X [][]float64, Y []float64) error {
+	if len(X) != len(Y) {
+		return fmt.Errorf("data and target mismatch")
+	}
+	if len(X) == 0 {
+		return nil
+	}
+	if r.clusterer == nil {
+		if r.Radius == 0 {
+			if len(X) < 2 {
+				return fmt.Errorf("at least 2 points are required to estimate the radius")
+			}
+			mean, std := nnStats(X, Distance)
+			r.Radius = mean + 2*std
+			if r.Verbose > 0 {
+				fmt.Println("=> radius=", r.Radius)
+			}
+		}
+		r.clusterer = NewClusterer(r.Radius, r.threshold, r.zones, r.outlier)
+	}
+
+	r.clusterer.mu.Lock()
+	defer r.clusterer.mu.Unlock()
+	r.clusterer.addPoints(X, nil, func(i int, mc *microcluster) {
+		if mc.Target == nil {
+			mc.Target = &targetStats{}
+		}
+		mc.Target.add(Y[i])
+	})
+	return nil
+}
+
+// PartialFit apprend une mesure 'x' de cible 'y'. Le rayon doit être connu.
+func (r *Regressor) PartialFit(x []float64, y float64) error {
+	if r.clusterer == nil && r.Radius == 0 {
+		return fmt.Errorf("radius must be set before incremental training")
+	}
+	return r.FitXY([][]float64{x}, []float64{y})
+}
+
+// Clusterer renvoie le Clusterer contenant les µC du Regressor
+func (r *Regressor) Clusterer() *Clusterer {
+	return r.clusterer
+}
+
+// PredictInterval prédit la cible de 'x' à partir des k µC les plus proches et renvoie l'intervalle de prédiction
+// [low, high] correspondant à 'z' écarts-types (1.96 pour 95% sous hypothèse gaussienne)
+func (r *Regressor) PredictInterval(x []float64, k int, z float64) (mean, low, high float64) {
+	if r.clusterer == nil {
+		return math.NaN(), math.NaN(), math.NaN()
+	}
+
+	type candidate struct {
+		distance float64
+		target   targetStats
+	}
+	candidates := []candidate{}
+	r.clusterer.mu.RLock()
+	for _, mc := range r.clusterer.mc {
+		if mc.Target != nil && mc.Target.Count > 0 {
+			candidates = append(candidates, candidate{distance: r.clusterer.distance(x, mc.Center), target: *mc.Target})
+		}
+	}
+	r.clusterer.mu.RUnlock()
+	if len(candidates) == 0 {
+		return math.NaN(), math.NaN(), math.NaN()
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].distance < candidates[j].distance })
+	if k < len(candidates) {
+		candidates = candidates[:k]
+	}
+
+	// moyenne et variance du mélange pondéré par l'inverse de la distance
+	total, moment2 := 0.0, 0.0
+	for _, cd := range candidates {
+		w := 1 / math.Max(cd.distance, 1e-12)
+		total += w
+		mean += w * cd.target.Mean
+		moment2 += w * (cd.target.variance() + cd.target.Mean*cd.target.Mean)
+	}
+	mean /= total
+	variance := math.Max(0, moment2/total-mean*mean)
+	delta := z * math.Sqrt(variance)
+	return mean, mean - delta, mean + delta
+}
+
+// Predict prédit la cible de chaque vecteur de 'x' à partir des k µC les plus proches
+func (r *Regressor) Predict(x [][]float64, k int) (y []float64) {
+	for _, vector := range x {
+		mean, _, _ := r.PredictInterval(vector, k, 0)
+		y = append(y, mean)
+	}
+	return y
+}
+
+type regressorJSON struct {
+	Clusterer *clustererJSON `json:"clusterer,omitempty"` // µC et statistiques de la cible
+	Radius    float64        `json:"radius"`
+	Threshold int            `json:"threshold"`
+	Zones     int            `json:"zones"`
+	Outlier   float64        `json:"outlier"`
+	Verbose   int            `json:"verbose"`
+}
+
+// ToJson exporte le Regressor en JSON
+func (r Regressor) ToJson() ([]byte, error) {
+	toExport := regressorJSON{
+		Radius:    r.Radius,
+		Threshold: r.threshold,
+		Zones:     r.zones,
+		Outlier:   r.outlier,
+		Verbose:   r.Verbose,
+	}
+	if r.clusterer != nil {
+		r.clusterer.mu.RLock()
+		defer r.clusterer.mu.RUnlock()
+		cl := r.clusterer.toJsonStruct()
+		toExport.Clusterer = &cl
+	}
+	return json.Marshal(toExport)
+}
+
+// NewRegressorFromJson crée un Regressor à partir d'un export JSON
+func NewRegressorFromJson(data []byte) (*Regressor, error) {
+	toImport := regressorJSON{}
+	if err := json.Unmarshal(data, &toImport); err != nil {
+		return nil, err
+	}
+
+	newRegressor := NewRegressor(toImport.Radius, toImport.Threshold, toImport.Zones, toImport.Outlier)
+	newRegressor.Verbose = toImport.Verbose
+	if toImport.Clusterer != nil {
+		d, err := json.Marshal(toImport.Clusterer)
+		if err != nil {
+			return nil, err
+		}
+		newRegressor.clusterer, err = NewClustererFromJson(d)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return newRegressor, nil
+}
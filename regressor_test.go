@@ -0,0 +1,59 @@
+package microClustering
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestRegressor(t *testing.T) {
+
+	// latence = 10*x + bruit, le bruit est plus fort pour x > 5 ; données reproductibles
+	rnd := rand.New(rand.NewSource(1))
+	X := [][]float64{}
+	Y := []float64{}
+	for i := 0; i < 500; i++ {
+		x := rnd.Float64() * 10
+		noise := 0.1
+		if x > 5 {
+			noise = 2
+		}
+		X = append(X, []float64{x})
+		Y = append(Y, 10*x+noise*rnd.NormFloat64())
+	}
+
+	SetDistanceFunction("euclidian")
+	r := NewRegressor(0.2, 1, 1, 3)
+	if err := r.FitXY(X, Y); err != nil {
+		t.Fatal(err)
+	}
+
+	y := r.Predict([][]float64{{2}, {8}}, 3)
+	fmt.Println("predictions :", y)
+	if math.Abs(y[0]-20) > 2 || math.Abs(y[1]-80) > 3 {
+		t.Errorf("predictions : %v", y)
+	}
+
+	_, low1, high1 := r.PredictInterval([]float64{2}, 3, 1.96)
+	_, low2, high2 := r.PredictInterval([]float64{8}, 3, 1.96)
+	if high1-low1 >= high2-low2 {
+		t.Errorf("interval should be wider where the target is noisier : [%f,%f] [%f,%f]", low1, high1, low2, high2)
+	}
+
+	if err := r.PartialFit([]float64{20}, 200); err != nil {
+		t.Fatal(err)
+	}
+
+	js, err := r.ToJson()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := NewRegressorFromJson(js)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if y2 := r2.Predict([][]float64{{20}}, 1); math.Abs(y2[0]-200) > 1e-9 {
+		t.Errorf("prediction after import : %v", y2)
+	}
+}
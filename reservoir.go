@@ -0,0 +1,112 @@
+package microClustering
+
+import (
+	"math/rand"
+)
+
+// Exemplar est une mesure réelle conservée dans le reservoir d'un µC
+type Exemplar struct {
+	Point   []float64   `json:"point"`             // mesure d'origine
+	Payload interface{} `json:"payload,omitempty"` // donnée opaque associée à la mesure (identifiant, enregistrement...)
+}
+
+// payload renvoie la donnée associée à la i-ème mesure si elle existe
+func payload(payloads []interface{}, i int) interface{} {
+	if payloads == nil {
+		return nil
+	}
+	return payloads[i]
+}
+
+// sample présente une mesure au reservoir du µC (algorithme R de Vitter) :
+// tant que le reservoir n'est pas plein la mesure est conservée, sinon elle remplace un exemple existant
+// avec une probabilité size/Seen, ce qui garantit un échantillon uniforme des mesures affectées au µC
+func (mc *microcluster) sample(m []float64, data interface{}, size int) {
+	if size <= 0 {
+		return
+	}
+	mc.Seen++
+
+	pos := len(mc.Exemplars)
+	if pos >= size {
+		pos = rand.Intn(mc.Seen)
+		if pos >= size {
+			return
+		}
+	}
+
+	point := make([]float64, len(m))
+	copy(point, m)
+	exemplar := Exemplar{Point: point, Payload: data}
+
+	if pos == len(mc.Exemplars) {
+		mc.Exemplars = append(mc.Exemplars, exemplar)
+	} else {
+		mc.Exemplars[pos] = exemplar
+	}
+}
+
+// Exemplars renvoie les mesures réelles conservées dans le reservoir du µC 'id'
+func (c *Clusterer) Exemplars(id int) []Exemplar {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if id < 0 || id >= len(c.mc) {
+		return nil
+	}
+	return append([]Exemplar{}, c.mc[id].Exemplars...)
+}
+
+// MacroClusters regroupe les µC représentatifs en macro-clusters :
+// deux µC appartiennent au même macro-cluster si leurs sphères se touchent (distance entre centres <= somme des rayons).
+// Renvoie pour chaque macro-cluster la liste des identifiants des µC qui le composent.
+func (c *Clusterer) MacroClusters() (macro [][]int) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.macroClusters()
+}
+
+// macroClusters réalise MacroClusters, le verrou étant déjà pris
+func (c *Clusterer) macroClusters() (macro [][]int) {
+	macroID := make([]int, len(c.mc))
+	for i := range macroID {
+		macroID[i] = -1
+	}
+
+	for i, mc := range c.mc {
+		if mc.Weight < c.minSize || macroID[i] != -1 {
+			continue
+		}
+		// parcours en largeur des µC connectés
+		id := len(macro)
+		macroID[i] = id
+		members := []int{i}
+		for next := 0; next < len(members); next++ {
+			current := c.mc[members[next]]
+			for j, other := range c.mc {
+				if macroID[j] != -1 || other.Weight < c.minSize {
+					continue
+				}
+				if c.distance(current.Center, other.Center) <= c.radiusOf(current)+c.radiusOf(other) {
+					macroID[j] = id
+					members = append(members, j)
+				}
+			}
+		}
+		macro = append(macro, members)
+	}
+	return macro
+}
+
+// MacroExemplars renvoie les mesures réelles conservées pour chaque macro-cluster, dans l'ordre de MacroClusters
+func (c *Clusterer) MacroExemplars() (exemplars [][]Exemplar) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, members := range c.macroClusters() {
+		list := []Exemplar{}
+		for _, id := range members {
+			list = append(list, c.mc[id].Exemplars...)
+		}
+		exemplars = append(exemplars, list)
+	}
+	return exemplars
+}
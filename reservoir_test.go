@@ -0,0 +1,59 @@
+package microClustering
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestReservoir(t *testing.T) {
+
+	data := [][]float64{{2.0, 2.0}, {1.0, 3.0}, {2.0, 8.0}, {2.0, 9.0}, {3, 8}, {4, 6}, {4, 7}, {4, 9}, {5, 7}, {5, 8}, {5, 9}, {6, 4}, {7, 5}, {9, 4}}
+	ids := []interface{}{}
+	for i := range data {
+		ids = append(ids, fmt.Sprintf("id-%d", i))
+	}
+
+	SetDistanceFunction("manhattan")
+	c := NewClusterer(2.0, 1, 1, 2)
+	c.ReservoirSize = 2
+
+	if err := c.AddWithPayload(data, ids); err != nil {
+		t.Fatal(err)
+	}
+
+	total := 0
+	for i := 0; i < c.CountMC(); i++ {
+		ex := c.Exemplars(i)
+		if len(ex) > c.ReservoirSize {
+			t.Errorf("µC %d : %d exemplars > %d", i, len(ex), c.ReservoirSize)
+		}
+		total += len(ex)
+	}
+	if total == 0 {
+		t.Error("no exemplar kept")
+	}
+
+	for i, ex := range c.MacroExemplars() {
+		fmt.Println("macro-cluster", i, ":", ex)
+	}
+
+	// les reservoirs ne sont exportés qu'à la demande
+	js, _ := c.ToJson()
+	c2, err := NewClustererFromJson(js)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c2.Exemplars(0)) != 0 {
+		t.Error("exemplars exported without ExportExemplars")
+	}
+
+	c.ExportExemplars = true
+	js, _ = c.ToJson()
+	c2, err = NewClustererFromJson(js)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c2.Exemplars(0)) != len(c.Exemplars(0)) {
+		t.Error("exemplars not restored from snapshot")
+	}
+}
@@ -0,0 +1,200 @@
+package microClustering
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+/*
+  Recherche d'hyperparamètres
+
+  - grille complète ou tirage aléatoire parmi les valeurs candidates de chaque paramètre
+  - le classifieur de base (SearchSpace.Base) conserve les réglages de l'appelant (règle de décision, outliers,
+    rayon par classe...) ; seuls les paramètres recherchés sont modifiés
+  - chaque jeu de paramètres est évalué par validation croisée stratifiée, sur les mêmes plis
+  - les évaluations sont réparties sur tous les coeurs
+  - le meilleur jeu de paramètres est ré-appris sur l'ensemble des données
+*/
+
+// Params regroupe les paramètres d'un classifieur et le nombre de voisins utilisé par KNN
+type Params struct {
+	Radius    float64 // rayon des µC (0 : estimation automatique)
+	Threshold int     // taille minimum des µC représentatifs
+	Zones     int     // nombre de zones concentriques
+	Outlier   float64 // nombre de sigmas pour la détection des outliers
+	K         int     // nombre de voisins
+	Distance  string  // nom de la fonction distance
+}
+
+func (p Params) String() string {
+	return fmt.Sprintf("radius=%g threshold=%d zones=%d outlier=%g k=%d distance=%s", p.Radius, p.Threshold, p.Zones, p.Outlier, p.K, p.Distance)
+}
+
+// newClassifier crée le classifieur de base puis lui applique les paramètres
+func (p Params) newClassifier(base func() *Classifier) *Classifier {
+	c := base()
+	c.Radius = p.Radius
+	c.threshold = p.Threshold
+	c.zones = p.Zones
+	c.outlier = p.Outlier
+	c.SetDistanceFunction(p.Distance)
+	return c
+}
+
+// SearchSpace liste les valeurs candidates de chaque paramètre.
+// Une liste vide utilise la valeur par défaut : rayon automatique, seuil 1, 1 zone, 3 sigmas, k=3 et fonction distance globale
+type SearchSpace struct {
+	Radius    []float64
+	Threshold []int
+	Zones     []int
+	Outlier   []float64
+	K         []int
+	Distance  []string
+
+	// Base crée un classifieur vide portant les réglages non recherchés (Decision, CheckOutliers, PerClassRadius...).
+	// nil : classifieur par défaut de NewClassifier
+	Base func() *Classifier
+}
+
+// withDefaults complète les listes vides avec les valeurs par défaut
+func (s SearchSpace) withDefaults() SearchSpace {
+	if len(s.Radius) == 0 {
+		s.Radius = []float64{0}
+	}
+	if len(s.Threshold) == 0 {
+		s.Threshold = []int{1}
+	}
+	if len(s.Zones) == 0 {
+		s.Zones = []int{1}
+	}
+	if len(s.Outlier) == 0 {
+		s.Outlier = []float64{3}
+	}
+	if len(s.K) == 0 {
+		s.K = []int{3}
+	}
+	if len(s.Distance) == 0 {
+		s.Distance = []string{distanceName}
+	}
+	if s.Base == nil {
+		s.Base = func() *Classifier { return NewClassifier(0, 0, 1, 1, 3) }
+	}
+	return s
+}
+
+// grid renvoie toutes les combinaisons de paramètres
+func (s SearchSpace) grid() (params []Params) {
+	for _, radius := range s.Radius {
+		for _, threshold := range s.Threshold {
+			for _, zones := range s.Zones {
+				for _, outlier := range s.Outlier {
+					for _, k := range s.K {
+						for _, distance := range s.Distance {
+							params = append(params, Params{Radius: radius, Threshold: threshold, Zones: zones, Outlier: outlier, K: k, Distance: distance})
+						}
+					}
+				}
+			}
+		}
+	}
+	return params
+}
+
+// SearchResult est le résultat de la validation croisée d'un jeu de paramètres
+type SearchResult struct {
+	Params Params
+	CV     CVResult
+	Err    error // erreur rencontrée lors de l'évaluation
+}
+
+// SearchReport regroupe les résultats d'une recherche, classés du meilleur au moins bon
+type SearchReport struct {
+	Results    []SearchResult
+	BestParams Params
+	Best       *Classifier // classifieur appris sur l'ensemble des données avec les meilleurs paramètres
+}
+
+// GridSearch évalue toutes les combinaisons de paramètres de 'space' par validation croisée stratifiée en 'nFolds' plis
+func GridSearch(X [][]float64, Y []int, space SearchSpace, nFolds int) (SearchReport, error) {
+	space = space.withDefaults()
+	return search(X, Y, space.grid(), space.Base, nFolds)
+}
+
+// RandomSearch évalue 'nIter' combinaisons de paramètres tirées aléatoirement parmi les valeurs de 'space'
+func RandomSearch(X [][]float64, Y []int, space SearchSpace, nFolds int, nIter int) (SearchReport, error) {
+	if nIter < 1 {
+		return SearchReport{}, fmt.Errorf("at least 1 iteration is required")
+	}
+	space = space.withDefaults()
+	grid := space.grid()
+	rand.Shuffle(len(grid), func(i, j int) { grid[i], grid[j] = grid[j], grid[i] })
+	if nIter < len(grid) {
+		grid = grid[:nIter]
+	}
+	return search(X, Y, grid, space.Base, nFolds)
+}
+
+// search évalue les jeux de paramètres en parallèle puis apprend le meilleur sur l'ensemble des données
+func search(X [][]float64, Y []int, candidates []Params, base func() *Classifier, nFolds int) (report SearchReport, err error) {
+	if len(X) != len(Y) {
+		return report, fmt.Errorf("data and label mismatch")
+	}
+	for _, p := range candidates {
+		if _, exists := distanceFunctions[p.Distance]; !exists {
+			return report, fmt.Errorf("unknown distance function %q", p.Distance)
+		}
+	}
+	folds, err := StratifiedKFold(Y, nFolds) // mêmes plis pour tous les candidats
+	if err != nil {
+		return report, err
+	}
+
+	report.Results = make([]SearchResult, len(candidates))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				p := candidates[i]
+				newClassifier := func() *Classifier { return p.newClassifier(base) }
+				cv, err := CrossValidate(X, Y, folds, newClassifier, p.K)
+				report.Results[i] = SearchResult{Params: p, CV: cv, Err: err}
+			}
+		}()
+	}
+	for i := range candidates {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	// classement : accuracy moyenne décroissante, puis écart-type et log-loss croissants
+	sort.SliceStable(report.Results, func(i, j int) bool {
+		a, b := report.Results[i], report.Results[j]
+		if (a.Err == nil) != (b.Err == nil) {
+			return a.Err == nil
+		}
+		if a.CV.Mean != b.CV.Mean {
+			return a.CV.Mean > b.CV.Mean
+		}
+		if a.CV.Std != b.CV.Std {
+			return a.CV.Std < b.CV.Std
+		}
+		return a.CV.LogLoss < b.CV.LogLoss
+	})
+
+	if len(report.Results) == 0 || report.Results[0].Err != nil {
+		return report, fmt.Errorf("no parameter set could be evaluated")
+	}
+	report.BestParams = report.Results[0].Params
+	report.Best = report.BestParams.newClassifier(base)
+	if err := report.Best.FitXY(X, Y); err != nil {
+		return report, err
+	}
+	return report, nil
+}
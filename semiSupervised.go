@@ -0,0 +1,302 @@
+package microClustering
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+/*
+  Apprentissage semi-supervisé par propagation de labels sur le graphe des µC
+
+  - toutes les mesures, étiquetées ou non, alimentent un Clusterer partagé
+  - les mesures étiquetées incrémentent le compteur de leur classe dans le µC qui les absorbe
+  - graphe des µC : une arête relie deux µC dont les centres sont à moins de GraphFactor*mcRadius,
+    son poids est la moyenne géométrique des poids des deux µC
+  - propagation (Zhou et al.) : F = Alpha*S*F + (1-Alpha)*Y, S étant la matrice des arêtes normalisée par ligne
+    et Y la distribution des labels observés dans chaque µC
+  - l'apprentissage (Add, AddLabelled) marque la propagation comme périmée : elle n'est recalculée qu'à la
+    prédiction suivante, une seule fois pour toutes les mesures apprises entre temps
+  - la prédiction utilise la distribution propagée des µC les plus proches
+*/
+
+// SemiSupervised est un classifieur semi-supervisé basé sur la propagation de labels entre µC.
+// Propagate doit être appelée pour prendre en compte une modification des paramètres.
+type SemiSupervised struct {
+	clusterer    *Clusterer
+	GraphFactor  float64 // distance maximum entre les centres de deux µC voisins, en nombre de rayons (2 par défaut)
+	Alpha        float64 // part de la propagation par rapport aux labels observés (0.9 par défaut)
+	MaxIter      int     // nombre maximum d'itérations de la propagation (100 par défaut)
+	UnknownLabel int     // classe renvoyée lorsqu'aucun µC voisin n'a reçu de label (-1 par défaut)
+
+	propagated map[*microcluster]map[int]float64 // distribution des labels propagés de chaque µC
+	dirty      bool                              // la propagation doit être recalculée avant la prochaine prédiction
+}
+
+// NewSemiSupervised crée un classifieur semi-supervisé dont les µC ont le rayon 'radius'
+func NewSemiSupervised(radius float64, threshold int, zones int, outlier float64) *SemiSupervised {
+	return &SemiSupervised{
+		clusterer:    NewClusterer(radius, threshold, zones, outlier),
+		UnknownLabel: -1,
+	}
+}
+
+// Clusterer renvoie le Clusterer partagé ; Propagate doit être appelée après l'avoir modifié directement
+func (s *SemiSupervised) Clusterer() *Clusterer {
+	return s.clusterer
+}
+
+// Add apprend des mesures non étiquetées ; les labels seront propagés à la prochaine prédiction
+func (s *SemiSupervised) Add(X [][]float64) {
+	if len(X) == 0 {
+		return
+	}
+	s.clusterer.mu.Lock()
+	defer s.clusterer.mu.Unlock()
+	s.clusterer.addPoints(X, nil, nil)
+	s.dirty = true
+}
+
+// AddLabelled apprend des mesures étiquetées : le µC qui absorbe chaque mesure compte une occurrence de son label.
+// Les labels seront propagés à la prochaine prédiction.
+func (s *SemiSupervised) AddLabelled(X [][]float64, Y []int) error {
+	if len(X) != len(Y) {
+		return fmt.Errorf("data and label mismatch")
+	}
+	if len(X) == 0 {
+		return nil
+	}
+	s.clusterer.mu.Lock()
+	defer s.clusterer.mu.Unlock()
+	s.clusterer.addPoints(X, nil, func(i int, mc *microcluster) {
+		if mc.Labels == nil {
+			mc.Labels = make(map[int]float64)
+		}
+		mc.Labels[Y[i]]++
+	})
+	s.dirty = true
+	return nil
+}
+
+// Propagate propage immédiatement les labels sur le graphe des µC
+func (s *SemiSupervised) Propagate() {
+	s.clusterer.mu.Lock() // la distribution propagée est lue sous le verrou du Clusterer
+	defer s.clusterer.mu.Unlock()
+	s.propagate()
+}
+
+// propagate propage les labels ; le verrou du Clusterer doit être pris en écriture
+func (s *SemiSupervised) propagate() {
+	c := s.clusterer
+	n := len(c.mc)
+
+	factor := s.GraphFactor
+	if factor <= 0 {
+		factor = 2
+	}
+	alpha := s.Alpha
+	if alpha <= 0 || alpha >= 1 {
+		alpha = 0.9
+	}
+	maxIter := s.MaxIter
+	if maxIter <= 0 {
+		maxIter = 100
+	}
+
+	// labels observés : distribution normalisée par µC
+	labelSet := make(map[int]bool)
+	for _, mc := range c.mc {
+		for label := range mc.Labels {
+			labelSet[label] = true
+		}
+	}
+	labels := []int{}
+	for label := range labelSet {
+		labels = append(labels, label)
+	}
+	sort.Ints(labels)
+
+	observed := make([][]float64, n)
+	for i, mc := range c.mc {
+		observed[i] = make([]float64, len(labels))
+		total := 0.0
+		for _, count := range mc.Labels {
+			total += count
+		}
+		for j, label := range labels {
+			if total > 0 {
+				observed[i][j] = mc.Labels[label] / total
+			}
+		}
+	}
+
+	// graphe des µC, normalisé par ligne
+	type edge struct {
+		to     int
+		weight float64
+	}
+	graph := make([][]edge, n)
+	for i := range c.mc {
+		sum := 0.0
+		for j := range c.mc {
+			if i == j || c.distance(c.mc[i].Center, c.mc[j].Center) > factor*c.mcRadius {
+				continue
+			}
+			w := math.Sqrt(float64(c.mc[i].Weight) * float64(c.mc[j].Weight))
+			graph[i] = append(graph[i], edge{to: j, weight: w})
+			sum += w
+		}
+		for e := range graph[i] {
+			graph[i][e].weight /= sum
+		}
+	}
+
+	// itérations
+	f := make([][]float64, n)
+	for i := range f {
+		f[i] = append([]float64{}, observed[i]...)
+	}
+	for iter := 0; iter < maxIter; iter++ {
+		next := make([][]float64, n)
+		change := 0.0
+		for i := range f {
+			next[i] = make([]float64, len(labels))
+			for j := range labels {
+				v := (1 - alpha) * observed[i][j]
+				for _, e := range graph[i] {
+					v += alpha * e.weight * f[e.to][j]
+				}
+				next[i][j] = v
+				change = math.Max(change, math.Abs(v-f[i][j]))
+			}
+		}
+		f = next
+		if change < 1e-9 {
+			break
+		}
+	}
+
+	// normalise la distribution de chaque µC
+	s.propagated = make(map[*microcluster]map[int]float64)
+	for i, mc := range c.mc {
+		total := 0.0
+		for _, v := range f[i] {
+			total += v
+		}
+		if total == 0 { // µC non relié à un µC étiqueté
+			continue
+		}
+		dist := make(map[int]float64)
+		for j, label := range labels {
+			dist[label] = f[i][j] / total
+		}
+		s.propagated[mc] = dist
+	}
+	s.dirty = false
+}
+
+// PredictProba renvoie la distribution des labels pour 'x' : moyenne des distributions propagées des k µC étiquetés
+// les plus proches, pondérée par l'inverse de la distance. La propagation est recalculée si elle est périmée.
+func (s *SemiSupervised) PredictProba(x []float64, k int) map[int]float64 {
+	s.clusterer.mu.RLock()
+	if s.dirty {
+		s.clusterer.mu.RUnlock()
+		s.clusterer.mu.Lock()
+		if s.dirty {
+			s.propagate()
+		}
+		s.clusterer.mu.Unlock()
+		s.clusterer.mu.RLock()
+	}
+	defer s.clusterer.mu.RUnlock()
+	type candidate struct {
+		distance float64
+		dist     map[int]float64
+	}
+	candidates := []candidate{}
+	for _, mc := range s.clusterer.mc {
+		if dist, exists := s.propagated[mc]; exists {
+			candidates = append(candidates, candidate{distance: s.clusterer.distance(x, mc.Center), dist: dist})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].distance < candidates[j].distance })
+	if k < len(candidates) {
+		candidates = candidates[:k]
+	}
+
+	proba := make(map[int]float64)
+	total := 0.0
+	for _, cd := range candidates {
+		w := 1 / math.Max(cd.distance, 1e-12)
+		for label, p := range cd.dist {
+			proba[label] += w * p
+		}
+		total += w
+	}
+	for label := range proba {
+		proba[label] /= total
+	}
+	return proba
+}
+
+// Predict renvoie le label le plus probable de chaque vecteur de 'x' (le plus petit label en cas d'égalité),
+// UnknownLabel si aucun µC étiqueté n'est accessible
+func (s *SemiSupervised) Predict(x [][]float64, k int) (y []int) {
+	for _, vector := range x {
+		best, bestProba := s.UnknownLabel, 0.0
+		for label, p := range s.PredictProba(vector, k) {
+			if p > bestProba || p == bestProba && best != s.UnknownLabel && label < best {
+				best, bestProba = label, p
+			}
+		}
+		y = append(y, best)
+	}
+	return y
+}
+
+type semiSupervisedJSON struct {
+	Clusterer    clustererJSON `json:"clusterer"` // µC et compteurs de labels
+	GraphFactor  float64       `json:"graph_factor,omitempty"`
+	Alpha        float64       `json:"alpha,omitempty"`
+	MaxIter      int           `json:"max_iter,omitempty"`
+	UnknownLabel int           `json:"unknown_label"`
+}
+
+// ToJson exporte le classifieur semi-supervisé en JSON ; les labels propagés sont recalculés à l'import
+func (s *SemiSupervised) ToJson() ([]byte, error) {
+	s.clusterer.mu.RLock()
+	defer s.clusterer.mu.RUnlock()
+	return json.Marshal(semiSupervisedJSON{
+		Clusterer:    s.clusterer.toJsonStruct(),
+		GraphFactor:  s.GraphFactor,
+		Alpha:        s.Alpha,
+		MaxIter:      s.MaxIter,
+		UnknownLabel: s.UnknownLabel,
+	})
+}
+
+// NewSemiSupervisedFromJson crée un classifieur semi-supervisé à partir d'un export JSON
+func NewSemiSupervisedFromJson(data []byte) (*SemiSupervised, error) {
+	toImport := semiSupervisedJSON{UnknownLabel: -1}
+	if err := json.Unmarshal(data, &toImport); err != nil {
+		return nil, err
+	}
+	d, err := json.Marshal(toImport.Clusterer)
+	if err != nil {
+		return nil, err
+	}
+	clusterer, err := NewClustererFromJson(d)
+	if err != nil {
+		return nil, err
+	}
+	s := &SemiSupervised{
+		clusterer:    clusterer,
+		GraphFactor:  toImport.GraphFactor,
+		Alpha:        toImport.Alpha,
+		MaxIter:      toImport.MaxIter,
+		UnknownLabel: toImport.UnknownLabel,
+		dirty:        true,
+	}
+	return s, nil
+}
@@ -0,0 +1,61 @@
+package microClustering
+
+import (
+	"testing"
+)
+
+func TestLabelPropagation(t *testing.T) {
+
+	// deux lignes parallèles de points, un seul point étiqueté par ligne
+	unlabelled := [][]float64{}
+	for i := 0; i < 50; i++ {
+		unlabelled = append(unlabelled, []float64{float64(i) * 0.2, 0}, []float64{float64(i) * 0.2, 3})
+	}
+
+	SetDistanceFunction("euclidian")
+	s := NewSemiSupervised(0.3, 1, 1, 3)
+	if err := s.AddLabelled(nil, nil); err != nil {
+		t.Errorf("empty labelled data : %v", err)
+	}
+	if y := s.Predict([][]float64{{0, 0}}, 1); y[0] != s.UnknownLabel {
+		t.Errorf("prediction without data : %v", y)
+	}
+	s.Add(unlabelled)
+	if err := s.AddLabelled([][]float64{{0, 0}, {0, 3}}, []int{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	// les labels se propagent le long de chaque ligne
+	y := s.Predict([][]float64{{9.5, 0.1}, {9.5, 2.9}, {5, 0}}, 1)
+	if y[0] != 1 || y[1] != 2 || y[2] != 1 {
+		t.Errorf("propagated labels : %v", y)
+	}
+
+	// la propagation n'est recalculée qu'à la prédiction suivant l'apprentissage
+	s.Add([][]float64{{10.2, 0}})
+	if !s.dirty || len(s.propagated) != s.Clusterer().CountMC()-1 {
+		t.Errorf("propagation recomputed while learning : %d labelled µC / %d", len(s.propagated), s.Clusterer().CountMC())
+	}
+	if y := s.Predict([][]float64{{10.2, 0}}, 1); y[0] != 1 || s.dirty {
+		t.Errorf("prediction after learning : %v", y)
+	}
+
+	// un point isolé n'est relié à aucun µC étiqueté
+	s.Add([][]float64{{100, 100}})
+	s.Predict([][]float64{{100, 100}}, 1)
+	if len(s.propagated) != s.Clusterer().CountMC()-1 {
+		t.Errorf("isolated µC received a label : %d labelled µC / %d", len(s.propagated), s.Clusterer().CountMC())
+	}
+
+	js, err := s.ToJson()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2, err := NewSemiSupervisedFromJson(js)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if y2 := s2.Predict([][]float64{{9.5, 2.9}}, 1); y2[0] != 2 {
+		t.Errorf("prediction after import : %v", y2)
+	}
+}
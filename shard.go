@@ -0,0 +1,299 @@
+package microClustering
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+)
+
+/*
+  Apprentissage parallèle et agrégation
+
+  - Clusterer.Merge fusionne les µC d'un autre Clusterer (shard local ou Clusterer d'un autre site) :
+    un µC dont le centre est dans la sphère d'un µC existant est fusionné avec le plus proche, en additionnant
+    poids, zones, cluster features et reservoirs ; sinon il est ajouté
+  - Classifier.Merge fusionne les classes de même label de deux Classifier
+  - ShardedClusterer répartit les mesures entre N Clusterer alimentés par des canaux, chacun dans sa goroutine :
+      - RoundRobinPartitioning : les lots de mesures sont répartis tour à tour entre les shards
+      - SpatialPartitioning : les mesures d'une même cellule de l'espace vont toujours au même shard,
+        ce qui limite le nombre de µC dupliqués entre shards
+  - les shards sont fusionnés dans une vue globale à la demande (Merge) ou périodiquement (MergeEvery)
+*/
+
+// Merge fusionne les µC de 'other' dans le Clusterer. Les deux Clusterer peuvent être utilisés pendant la fusion.
+func (c *Clusterer) Merge(other *Clusterer) error {
+	if other == c {
+		return fmt.Errorf("cannot merge a clusterer with itself")
+	}
+	// copie de 'other' : les deux verrous ne sont jamais pris ensemble
+	other.mu.RLock()
+	snapshot := &Clusterer{vectorSize: other.vectorSize, tick: other.tick, mc: make([]*microcluster, len(other.mc))}
+	for i, mc := range other.mc {
+		snapshot.mc[i] = mc.copy()
+	}
+	other.mu.RUnlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.vectorSize != 0 && snapshot.vectorSize != 0 && c.vectorSize != snapshot.vectorSize {
+		return fmt.Errorf("vector size mismatch: %d and %d", c.vectorSize, snapshot.vectorSize)
+	}
+	tick := c.tick
+	c.absorbClusterer(snapshot)
+	c.tick = tick + snapshot.tick // les mesures des deux Clusterer ont été traitées
+	return nil
+}
+
+// Merge fusionne chaque classe de 'other' dans la classe de même label, créée avec les paramètres de 'other' si
+// elle n'existe pas. Les libellés textuels de 'other' sont réencodés par l'encodeur du Classifier : deux classes
+// de même libellé sont fusionnées quel que soit leur code. Les mesures du buffer de démarrage de 'other' sont
+// ajoutées à celui du Classifier.
+func (c *Classifier) Merge(other *Classifier) error {
+	if other == c {
+		return fmt.Errorf("cannot merge a classifier with itself")
+	}
+	if c.Labels == nil && other.Labels != nil && len(c.classes) == 0 && len(c.warmUpLabels) == 0 {
+		c.Labels = NewLabelEncoder()
+	}
+	if (c.Labels == nil) != (other.Labels == nil) {
+		return fmt.Errorf("cannot merge a classifier with string labels and a classifier without")
+	}
+	// code dans le Classifier de la classe 'label' de 'other'
+	code := func(label int) int {
+		if other.Labels == nil {
+			return label
+		}
+		if name, exists := other.Labels.Decode(label); exists {
+			return c.Labels.encode(name)
+		}
+		return label
+	}
+
+	for _, label := range other.labels() {
+		from := other.classes[label]
+		target := code(label)
+		into, exists := c.classes[target]
+		if !exists {
+			into = from.newEmpty()
+			c.classes[target] = into
+			if r, known := other.ClassRadius[label]; known && c.PerClassRadius {
+				if c.ClassRadius == nil {
+					c.ClassRadius = make(map[int]float64)
+				}
+				c.ClassRadius[target] = r
+			}
+		}
+		if err := into.Merge(from); err != nil {
+			return fmt.Errorf("class %d: %v", label, err)
+		}
+		c.seen(target)
+	}
+	for i, x := range other.warmUpData {
+		c.warmUpData = append(c.warmUpData, append([]float64{}, x...))
+		c.warmUpLabels = append(c.warmUpLabels, code(other.warmUpLabels[i]))
+	}
+	return nil
+}
+
+// newEmpty crée un Clusterer vide ayant les mêmes paramètres
+func (c *Clusterer) newEmpty() *Clusterer {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	newClusterer := NewClusterer(c.mcRadius, c.minSize, c.zones, c.outlierThreshold)
+	newClusterer.distFunction = c.distFunction
+	newClusterer.distance = c.distance
+	newClusterer.ReservoirSize = c.ReservoirSize
+	newClusterer.ExportExemplars = c.ExportExemplars
+	newClusterer.RadiusMode = c.RadiusMode
+	newClusterer.RadiusFactor = c.RadiusFactor
+	newClusterer.MaxRadius = c.MaxRadius
+	newClusterer.MaxMicroClusters = c.MaxMicroClusters
+	newClusterer.CapacityPolicy = c.CapacityPolicy
+	newClusterer.TrackFeatures = c.TrackFeatures
+	return newClusterer
+}
+
+// Partitioning définit la répartition des mesures entre les shards
+type Partitioning int
+
+const (
+	// RoundRobinPartitioning : les lots de mesures sont envoyés tour à tour à chaque shard
+	RoundRobinPartitioning Partitioning = iota
+	// SpatialPartitioning : chaque mesure est envoyée au shard de sa cellule de l'espace
+	SpatialPartitioning
+)
+
+// ShardedClusterer répartit l'apprentissage entre plusieurs Clusterer fonctionnant en parallèle
+type ShardedClusterer struct {
+	Partitioning Partitioning // répartition des mesures (à choisir avant le premier Add)
+	CellSize     float64      // côté des cellules du partitionnement spatial (10 rayons par défaut)
+
+	shards []*Clusterer
+	inputs []chan [][]float64
+	next   int // prochain shard en RoundRobinPartitioning
+	radius float64
+
+	mu      sync.Mutex
+	pending int        // nombre de lots envoyés non encore appris
+	done    *sync.Cond // signalé lorsque pending revient à 0
+	global  *Clusterer // dernière vue globale
+	workers sync.WaitGroup
+	stop    chan struct{}
+
+	sending sync.RWMutex // empêche la fermeture des canaux pendant un envoi
+	closed  bool
+}
+
+// NewShardedClusterer crée 'shards' Clusterer de paramètres identiques et démarre leurs goroutines
+func NewShardedClusterer(shards int, radius float64, minSize int, zones int, outlierThreshold float64) *ShardedClusterer {
+	if shards < 1 {
+		shards = 1
+	}
+	s := &ShardedClusterer{radius: radius, stop: make(chan struct{})}
+	s.done = sync.NewCond(&s.mu)
+	for i := 0; i < shards; i++ {
+		shard := NewClusterer(radius, minSize, zones, outlierThreshold)
+		input := make(chan [][]float64, 16) // la taille du tampon limite la mémoire en cas de shard lent
+		s.shards = append(s.shards, shard)
+		s.inputs = append(s.inputs, input)
+		s.workers.Add(1)
+		go s.work(shard, input)
+	}
+	s.global = s.shards[0].newEmpty()
+	return s
+}
+
+// work apprend les lots reçus par le shard
+func (s *ShardedClusterer) work(shard *Clusterer, input chan [][]float64) {
+	defer s.workers.Done()
+	for batch := range input {
+		shard.Add(batch)
+		s.mu.Lock()
+		s.pending--
+		if s.pending == 0 {
+			s.done.Broadcast()
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Shards renvoie les Clusterer de chaque shard, par exemple pour les paramétrer avant le premier Add
+func (s *ShardedClusterer) Shards() []*Clusterer {
+	return s.shards
+}
+
+// Add répartit les mesures de 'm' entre les shards. L'appel bloque si les shards ne suivent pas le rythme.
+func (s *ShardedClusterer) Add(m [][]float64) error {
+	s.sending.RLock()
+	defer s.sending.RUnlock()
+	if s.closed {
+		return fmt.Errorf("sharded clusterer is closed")
+	}
+
+	batches := make([][][]float64, len(s.shards))
+	s.mu.Lock()
+	if s.Partitioning == SpatialPartitioning {
+		for _, x := range m {
+			id := s.cell(x)
+			batches[id] = append(batches[id], x)
+		}
+	} else {
+		batches[s.next] = m
+		s.next = (s.next + 1) % len(s.shards)
+	}
+	for _, batch := range batches {
+		if len(batch) > 0 {
+			s.pending++
+		}
+	}
+	s.mu.Unlock()
+
+	for id, batch := range batches {
+		if len(batch) > 0 {
+			s.inputs[id] <- batch
+		}
+	}
+	return nil
+}
+
+// cell renvoie le shard de la cellule contenant la mesure 'x'
+func (s *ShardedClusterer) cell(x []float64) int {
+	size := s.CellSize
+	if size <= 0 {
+		size = 10 * s.radius
+	}
+	h := fnv.New64a()
+	buf := make([]byte, 8)
+	for _, v := range x {
+		c := int64(math.Floor(v / size))
+		for i := range buf {
+			buf[i] = byte(c >> (8 * uint(i)))
+		}
+		h.Write(buf)
+	}
+	return int(h.Sum64() % uint64(len(s.shards)))
+}
+
+// Flush attend que toutes les mesures envoyées aient été apprises par les shards
+func (s *ShardedClusterer) Flush() {
+	s.mu.Lock()
+	for s.pending > 0 {
+		s.done.Wait()
+	}
+	s.mu.Unlock()
+}
+
+// Merge fusionne l'état courant des shards dans une nouvelle vue globale et la renvoie.
+// Les mesures encore en attente dans les canaux ne sont pas prises en compte (voir Flush).
+func (s *ShardedClusterer) Merge() *Clusterer {
+	global := s.shards[0].newEmpty()
+	for _, shard := range s.shards {
+		global.Merge(shard)
+	}
+	s.mu.Lock()
+	s.global = global
+	s.mu.Unlock()
+	return global
+}
+
+// Global renvoie la dernière vue globale calculée par Merge
+func (s *ShardedClusterer) Global() *Clusterer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.global
+}
+
+// MergeEvery fusionne les shards dans la vue globale toutes les 'interval' jusqu'à Close
+func (s *ShardedClusterer) MergeEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.Merge()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Close attend l'apprentissage des mesures envoyées, arrête les goroutines et renvoie la vue globale finale
+func (s *ShardedClusterer) Close() *Clusterer {
+	s.sending.Lock()
+	if s.closed {
+		s.sending.Unlock()
+		return s.Global()
+	}
+	s.closed = true
+	close(s.stop)
+	for _, input := range s.inputs {
+		close(input)
+	}
+	s.sending.Unlock()
+	s.workers.Wait()
+	return s.Merge()
+}
@@ -0,0 +1,197 @@
+package microClustering
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	a := NewClusterer(1, 1, 2, 3)
+	a.TrackFeatures = true
+	a.Add([][]float64{{0, 0}, {0.2, 0}})
+	b := NewClusterer(1, 1, 2, 3)
+	b.TrackFeatures = true
+	b.Add([][]float64{{0.4, 0}, {10, 10}})
+
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if a.CountMC() != 2 {
+		t.Fatalf("expected 2 µC after merge, got %d", a.CountMC())
+	}
+	mc := a.mc[0]
+	if mc.Weight != 3 || mc.Zones[0]+mc.Zones[1] != 3 {
+		t.Errorf("merged µC weight %d zones %v, expected 3 points", mc.Weight, mc.Zones)
+	}
+	if math.Abs(mc.Center[0]-0.2) > 1e-9 || math.Abs(mc.LS[0]-0.6) > 1e-9 {
+		t.Errorf("merged center %v LS %v, expected exact mean 0.2", mc.Center, mc.LS)
+	}
+	if b.CountMC() != 2 || b.mc[0].Weight != 1 {
+		t.Error("merged clusterer should not be modified")
+	}
+
+	// zones redistribuées proportionnellement à leur rayon
+	d := NewClusterer(1, 1, 4, 3)
+	d.Add([][]float64{{10, 10}, {10.1, 10}, {10.3, 10}, {10.6, 10}, {10.9, 10}})
+	rebinned := rebinZones(d.mc[0].Zones, 2)
+	expected := fmt.Sprint([]int{a.mc[1].Zones[0] + rebinned[0], a.mc[1].Zones[1] + rebinned[1]})
+	if err := a.Merge(d); err != nil {
+		t.Fatal(err)
+	}
+	if mc := a.mc[1]; fmt.Sprint(mc.Zones) != expected || mc.Weight != 6 {
+		t.Errorf("merged µC zones %v weight %d, expected %s and 6", mc.Zones, mc.Weight, expected)
+	}
+	for _, test := range []struct {
+		zones    []int
+		n        int
+		expected string
+	}{
+		{[]int{4, 2}, 4, "[2 2 1 1]"},
+		{[]int{1, 2, 1, 0}, 2, "[3 1]"},
+		{[]int{3}, 2, "[2 1]"},
+	} {
+		if got := fmt.Sprint(rebinZones(test.zones, test.n)); got != test.expected {
+			t.Errorf("zones %v rebinned in %d: %s, expected %s", test.zones, test.n, got, test.expected)
+		}
+	}
+
+	c := NewClusterer(1, 1, 2, 3)
+	c.Add([][]float64{{0, 0, 0}})
+	if err := a.Merge(c); err == nil {
+		t.Error("merging vectors of different sizes should fail")
+	}
+	if err := a.Merge(a); err == nil {
+		t.Error("merging a clusterer with itself should fail")
+	}
+}
+
+func TestShardedClusterer(t *testing.T) {
+	X, _ := blobs(500, [][]float64{{0, 0}, {10, 0}, {0, 10}, {10, 10}}, 1)
+
+	single := NewClusterer(1, 1, 1, 3)
+	single.Add(X)
+
+	for _, p := range []Partitioning{RoundRobinPartitioning, SpatialPartitioning} {
+		s := NewShardedClusterer(4, 1, 1, 1, 3)
+		s.Partitioning = p
+		s.MergeEvery(time.Millisecond)
+		for i := 0; i < len(X); i += 10 {
+			if err := s.Add(X[i : i+10]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		s.Flush()
+		global := s.Close()
+		if err := s.Add(X[:1]); err == nil {
+			t.Error("Add after Close should fail")
+		}
+
+		fmt.Println("partitioning", p, ":", global.CountMC(), "µC, single clusterer :", single.CountMC())
+		if w := global.totalWeight(); w != len(X) {
+			t.Errorf("partitioning %d: global weight %d, expected %d", p, w, len(X))
+		}
+		if global.tick != int64(len(X)) {
+			t.Errorf("partitioning %d: %d points processed, expected %d", p, global.tick, len(X))
+		}
+		if global.CountMC() > 2*single.CountMC() {
+			t.Errorf("partitioning %d: %d µC, single clusterer has %d", p, global.CountMC(), single.CountMC())
+		}
+		if global.IsOutlier([]float64{10, 10}) || !global.IsOutlier([]float64{5, 5}) {
+			t.Errorf("partitioning %d: global view does not match the data", p)
+		}
+	}
+}
+
+func BenchmarkSingleAdd(b *testing.B) {
+	X, _ := blobs(2500, [][]float64{{0, 0}, {10, 0}, {0, 10}, {10, 10}}, 2)
+	for i := 0; i < b.N; i++ {
+		c := NewClusterer(0.5, 1, 1, 3)
+		c.Add(X)
+	}
+}
+
+func BenchmarkShardedAdd(b *testing.B) {
+	X, _ := blobs(2500, [][]float64{{0, 0}, {10, 0}, {0, 10}, {10, 10}}, 2)
+	for i := 0; i < b.N; i++ {
+		s := NewShardedClusterer(4, 0.5, 1, 1, 3)
+		s.Partitioning = SpatialPartitioning
+		for j := 0; j < len(X); j += 100 {
+			s.Add(X[j : j+100])
+		}
+		s.Close()
+	}
+}
+
+func TestClassifierMerge(t *testing.T) {
+	X, Y := blobs(100, [][]float64{{0, 0}, {10, 0}}, 1)
+	a := NewClassifier(0, 1, 1, 1, 3)
+	a.FitXY(X[:100], Y[:100])
+	b := NewClassifier(0, 1, 1, 1, 3)
+	b.FitXY(X[100:], Y[100:])
+
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	for label, cl := range a.classes {
+		if w := cl.totalWeight(); w != 100 {
+			t.Errorf("class %d: weight %d after merge, expected 100", label, w)
+		}
+	}
+	if err := a.Merge(a); err == nil {
+		t.Error("merging a classifier with itself should fail")
+	}
+}
+
+func TestClassifierMergeLabels(t *testing.T) {
+	fit := func(names []string, centers [][]float64) *Classifier {
+		X, Y := blobs(50, centers, 1)
+		labels := make([]string, len(Y))
+		for i, y := range Y {
+			labels[i] = names[y]
+		}
+		c := NewClassifier(0, 1, 1, 1, 3)
+		if err := c.FitXYStrings(X, labels); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	// 'a' apprend cat et dog, 'b' apprend dog et bird : dog n'a pas le même code
+	a := fit([]string{"cat", "dog"}, [][]float64{{0, 0}, {10, 0}})
+	b := fit([]string{"dog", "bird"}, [][]float64{{10, 0}, {0, 10}})
+
+	if err := a.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]int{"cat": 50, "dog": 100, "bird": 50}
+	for name, weight := range expected {
+		code, exists := a.Labels.codes[name]
+		if !exists {
+			t.Errorf("label %s missing after merge", name)
+			continue
+		}
+		if w := a.classes[code].totalWeight(); w != weight {
+			t.Errorf("label %s: weight %d after merge, expected %d", name, w, weight)
+		}
+	}
+	if got := a.KNNStrings([][]float64{{0, 10}, {10, 0}}, 1); fmt.Sprint(got) != "[bird dog]" {
+		t.Errorf("predicted %v after merge, expected [bird dog]", got)
+	}
+
+	// un Classifier vide adopte les libellés
+	empty := NewClassifier(0, 1, 1, 1, 3)
+	if err := empty.Merge(b); err != nil {
+		t.Fatal(err)
+	}
+	if got := empty.KNNStrings([][]float64{{0, 10}}, 1); fmt.Sprint(got) != "[bird]" {
+		t.Errorf("predicted %v after merge into an empty classifier, expected [bird]", got)
+	}
+
+	X, Y := blobs(50, [][]float64{{0, 0}}, 1)
+	unlabelled := NewClassifier(0, 1, 1, 1, 3)
+	unlabelled.FitXY(X, Y)
+	if err := unlabelled.Merge(b); err == nil {
+		t.Error("merging labelled classes into an unlabelled classifier should fail")
+	}
+}
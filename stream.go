@@ -0,0 +1,336 @@
+package microClustering
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+  Apprentissage d'un flux de mesures
+
+  - Consume apprend les mesures reçues sur un canal, ConsumeReader décode les mesures d'un io.Reader
+    (CSV, JSON Lines ou flottants binaires préfixés par leur nombre) puis les apprend comme Consume
+  - les mesures sont apprises par lots de Stream.BatchSize : le verrou du Clusterer n'est pris qu'une fois par lot
+    et les requêtes concurrentes restent possibles entre deux lots
+  - contre-pression : une mesure n'est lue qu'une fois le lot précédent appris, l'émetteur est donc ralenti
+    au rythme de l'apprentissage
+  - l'annulation du contexte arrête la lecture : le lot en cours est appris puis ctx.Err() est renvoyée
+  - toutes les Forgetting.Every mesures, l'oubli est appliqué puis OnForget est appelée
+  - les mesures mal formées (valeur illisible, taille incorrecte, NaN) sont ignorées et signalées sur Errors
+*/
+
+// defaultBatchSize est la taille par défaut des lots appris par Consume
+const defaultBatchSize = 100
+
+// maxBinaryValues limite la taille d'une mesure binaire, pour ne pas allouer une taille corrompue
+const maxBinaryValues = 1 << 20
+
+// StreamFormat définit l'encodage des mesures lues par ConsumeReader
+type StreamFormat int
+
+const (
+	// CSVFormat : une mesure par ligne, valeurs séparées par Stream.Comma
+	CSVFormat StreamFormat = iota
+	// JSONLinesFormat : une mesure par ligne, sous la forme d'un tableau JSON de nombres
+	JSONLinesFormat
+	// BinaryFormat : pour chaque mesure, le nombre de valeurs (uint32) suivi des valeurs (float64), en little endian
+	BinaryFormat
+)
+
+// Stream paramètre l'apprentissage d'un flux par Consume et ConsumeReader
+type Stream struct {
+	BatchSize int           // nombre de mesures apprises à chaque prise du verrou (100 par défaut)
+	MaxDelay  time.Duration // délai maximum avant l'apprentissage d'un lot incomplet (0 : attend que le lot soit complet)
+
+	Forgetting Forgetting         // oubli (RandomDelete) appliqué toutes les Forgetting.Every mesures apprises (Fade n'est pas utilisé)
+	OnForget   func(c *Clusterer) // appelée toutes les Forgetting.Every mesures apprises, après l'oubli, sans verrou
+
+	Errors chan<- error // reçoit une RowError pour chaque mesure ignorée (nil : pas de signalement). L'envoi est bloquant.
+
+	Comma  rune // séparateur CSV (',' par défaut)
+	Header bool // la première ligne CSV est un en-tête
+}
+
+// NewStream crée des paramètres de flux par défaut
+func NewStream() *Stream {
+	return &Stream{BatchSize: defaultBatchSize, Comma: ','}
+}
+
+// RowError décrit une mesure ignorée du flux
+type RowError struct {
+	Row int   // numéro de la mesure dans le flux (de la ligne pour JSON Lines), à partir de 1
+	Err error // cause
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+}
+
+// stream renvoie les paramètres de flux du Clusterer
+func (c *Clusterer) stream() *Stream {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.Stream == nil {
+		return NewStream()
+	}
+	return c.Stream
+}
+
+// report signale une mesure ignorée sur s.Errors, sauf si le contexte est annulé
+func (s *Stream) report(ctx context.Context, row int, err error) {
+	if s.Errors == nil {
+		return
+	}
+	select {
+	case s.Errors <- &RowError{Row: row, Err: err}:
+	case <-ctx.Done():
+	}
+}
+
+// checkRow vérifie une mesure, 'size' étant la taille attendue (0 : fixée par la première mesure valide)
+func checkRow(x []float64, size *int) error {
+	if len(x) == 0 {
+		return fmt.Errorf("empty vector")
+	}
+	if *size != 0 && len(x) != *size {
+		return fmt.Errorf("vector size %d, expected %d", len(x), *size)
+	}
+	for i, v := range x {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("invalid value at column %d", i+1)
+		}
+	}
+	*size = len(x)
+	return nil
+}
+
+// vectorSizeOf renvoie la taille des mesures déjà apprises (0 si aucune)
+func (c *Clusterer) vectorSizeOf() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.vectorSize
+}
+
+// Consume apprend les mesures reçues sur 'in' jusqu'à la fermeture du canal (nil est renvoyé)
+// ou l'annulation du contexte (ctx.Err() est renvoyée). Les paramètres sont ceux de c.Stream.
+func (c *Clusterer) Consume(ctx context.Context, in <-chan []float64) error {
+	s := c.stream()
+	batchSize := s.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	var timeout <-chan time.Time
+	if s.MaxDelay > 0 {
+		ticker := time.NewTicker(s.MaxDelay)
+		defer ticker.Stop()
+		timeout = ticker.C
+	}
+
+	size := c.vectorSizeOf()
+	batch := make([][]float64, 0, batchSize)
+	row, learned := 0, 0
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		c.Add(batch)
+		learned += len(batch)
+		batch = make([][]float64, 0, batchSize)
+		if f := s.Forgetting; f.Every > 0 && learned >= f.Every {
+			learned = 0
+			if f.Pct > 0 {
+				proba := f.Proba
+				if proba <= 0 {
+					proba = 1
+				}
+				c.RandomDelete(f.Pct, proba)
+			}
+			if s.OnForget != nil {
+				s.OnForget(c)
+			}
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			flush()
+			return ctx.Err()
+		case <-timeout:
+			flush()
+		case x, ok := <-in:
+			if !ok {
+				flush()
+				return nil
+			}
+			row++
+			if err := checkRow(x, &size); err != nil {
+				s.report(ctx, row, err)
+				continue
+			}
+			batch = append(batch, x)
+			if len(batch) >= batchSize {
+				flush()
+			}
+		}
+	}
+}
+
+// ConsumeReader décode les mesures de 'r' au format 'format' et les apprend comme Consume.
+// Une erreur de lecture de 'r' ou un enregistrement binaire tronqué arrête l'apprentissage et est renvoyée.
+// Une lecture bloquée de 'r' n'est pas interrompue par l'annulation du contexte.
+func (c *Clusterer) ConsumeReader(ctx context.Context, r io.Reader, format StreamFormat) error {
+	s := c.stream()
+	buffer := s.BatchSize // le décodage peut avancer d'un lot sur l'apprentissage
+	if buffer <= 0 {
+		buffer = defaultBatchSize
+	}
+	rows := make(chan []float64, buffer)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	decoded := make(chan error, 1)
+	go func() {
+		defer close(rows)
+		decoded <- s.decode(ctx, r, format, c.vectorSizeOf(), rows)
+	}()
+	err := c.Consume(ctx, rows)
+	cancel() // arrête le décodage si Consume s'est arrêtée avant la fin du flux
+	if decodeErr := <-decoded; decodeErr != nil && decodeErr != context.Canceled {
+		return decodeErr
+	}
+	return err
+}
+
+// Decode envoie sur 'out' les mesures valides lues dans 'r' au format 'format', puis ferme 'out'.
+// Les mesures ignorées sont signalées sur s.Errors comme pour ConsumeReader.
+func (s *Stream) Decode(ctx context.Context, r io.Reader, format StreamFormat, out chan<- []float64) error {
+	defer close(out)
+	return s.decode(ctx, r, format, 0, out)
+}
+
+// decode envoie sur 'rows' les mesures valides de 'r', 'size' étant la taille attendue (0 : inconnue)
+func (s *Stream) decode(ctx context.Context, r io.Reader, format StreamFormat, size int, rows chan<- []float64) error {
+	send := func(row int, x []float64, err error) error {
+		if err == nil {
+			err = checkRow(x, &size)
+		}
+		if err != nil {
+			s.report(ctx, row, err)
+			return ctx.Err()
+		}
+		select {
+		case rows <- x:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	switch format {
+	case CSVFormat:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1 // la taille est vérifiée par checkRow
+		reader.ReuseRecord = true
+		if s.Comma != 0 {
+			reader.Comma = s.Comma
+		}
+		for row := 1; ; row++ {
+			record, err := reader.Read()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				if _, malformed := err.(*csv.ParseError); !malformed {
+					return err
+				}
+			} else if row == 1 && s.Header {
+				continue
+			}
+			var x []float64
+			if err == nil {
+				x, err = parseFields(record)
+			}
+			if err := send(row, x, err); err != nil {
+				return err
+			}
+		}
+
+	case JSONLinesFormat:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for row := 1; scanner.Scan(); row++ {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			var x []float64
+			err := json.Unmarshal([]byte(line), &x)
+			if err := send(row, x, err); err != nil {
+				return err
+			}
+		}
+		return scanner.Err()
+
+	case BinaryFormat:
+		reader := bufio.NewReader(r)
+		for row := 1; ; row++ {
+			var n uint32
+			if err := binary.Read(reader, binary.LittleEndian, &n); err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return fmt.Errorf("row %d: %v", row, err)
+			}
+			if n > maxBinaryValues {
+				return fmt.Errorf("row %d: invalid vector size %d", row, n)
+			}
+			x := make([]float64, n)
+			if err := binary.Read(reader, binary.LittleEndian, x); err != nil {
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return fmt.Errorf("row %d: %v", row, err)
+			}
+			if err := send(row, x, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return fmt.Errorf("unknown stream format %d", format)
+}
+
+// parseFields convertit les champs d'une ligne CSV en mesure
+func parseFields(fields []string) ([]float64, error) {
+	x := make([]float64, len(fields))
+	for i, field := range fields {
+		v, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return nil, fmt.Errorf("column %d: invalid value %q", i+1, field)
+		}
+		x[i] = v
+	}
+	return x, nil
+}
+
+// WriteBinary écrit les mesures de 'm' dans 'w' au format BinaryFormat
+func WriteBinary(w io.Writer, m [][]float64) error {
+	for _, x := range m {
+		if err := binary.Write(w, binary.LittleEndian, uint32(len(x))); err != nil {
+			return err
+		}
+		if err := binary.Write(w, binary.LittleEndian, x); err != nil {
+			return err
+		}
+	}
+	return nil
+}
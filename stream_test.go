@@ -0,0 +1,164 @@
+package microClustering
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConsume(t *testing.T) {
+	X, _ := blobs(300, [][]float64{{0, 0}, {10, 10}}, 1)
+	errors := make(chan error, 10)
+	forgets := 0
+
+	c := NewClusterer(1, 1, 2, 3)
+	c.Stream = NewStream()
+	c.Stream.BatchSize = 32
+	c.Stream.Errors = errors
+	c.Stream.Forgetting = Forgetting{Every: 200, Pct: 0.1}
+	c.Stream.OnForget = func(c *Clusterer) {
+		forgets++
+		c.CountMC() // le verrou n'est pas pris pendant l'appel
+	}
+
+	in := make(chan []float64)
+	go func() {
+		for i, x := range X {
+			if i == 10 {
+				in <- []float64{1, 2, 3}
+			}
+			in <- x
+		}
+		close(in)
+	}()
+	if err := c.Consume(context.Background(), in); err != nil {
+		t.Fatal(err)
+	}
+	if c.tick != int64(len(X)) {
+		t.Errorf("%d points learned, expected %d", c.tick, len(X))
+	}
+	if forgets != 2 || c.totalWeight() >= len(X) { // 600 mesures apprises par lots de 32 : oubli à 224 et 448
+		t.Errorf("forgetting applied %d times, weight %d", forgets, c.totalWeight())
+	}
+	select {
+	case err := <-errors:
+		if rowErr, ok := err.(*RowError); !ok || rowErr.Row != 11 {
+			t.Errorf("unexpected error %v", err)
+		}
+	default:
+		t.Error("wrong vector size should be reported")
+	}
+
+	// annulation : le lot en cours est appris
+	ctx, cancel := context.WithCancel(context.Background())
+	in = make(chan []float64)
+	c.Stream.MaxDelay = time.Millisecond
+	done := make(chan error)
+	go func() { done <- c.Consume(ctx, in) }()
+	in <- []float64{20, 20}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if c.KNN([]float64{20, 20}, 1)[0].distance != 0 {
+		t.Error("pending point should be learned on cancellation")
+	}
+}
+
+func TestConsumeReader(t *testing.T) {
+	var binary bytes.Buffer
+	WriteBinary(&binary, [][]float64{{0, 0}, {0.1, 0}, {1, 2, 3}, {0, 0.1}})
+
+	tests := []struct {
+		name    string
+		format  StreamFormat
+		data    string
+		learned int
+		errors  []int // lignes mal formées
+	}{
+		{"csv", CSVFormat, "x,y\n0,0\n0.1,0\n0,abc\n1,2,3\n0,0.1\nNaN,0\n", 3, []int{4, 5, 7}},
+		{"jsonl", JSONLinesFormat, "[0,0]\n[0.1,0]\n{}\n\n[0,0.1]\n[1]\n", 3, []int{3, 6}},
+		{"binary", BinaryFormat, binary.String(), 3, []int{3}},
+	}
+	for _, test := range tests {
+		c := NewClusterer(1, 1, 2, 3)
+		errors := make(chan error, 10)
+		c.Stream = NewStream()
+		c.Stream.Errors = errors
+		c.Stream.Header = test.format == CSVFormat
+		if err := c.ConsumeReader(context.Background(), strings.NewReader(test.data), test.format); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		close(errors)
+		if c.tick != int64(test.learned) {
+			t.Errorf("%s: %d points learned, expected %d", test.name, c.tick, test.learned)
+		}
+		var rows []int
+		for err := range errors {
+			rows = append(rows, err.(*RowError).Row)
+		}
+		if len(rows) != len(test.errors) {
+			t.Errorf("%s: malformed rows %v, expected %v", test.name, rows, test.errors)
+			continue
+		}
+		for i := range rows {
+			if rows[i] != test.errors[i] {
+				t.Errorf("%s: malformed rows %v, expected %v", test.name, rows, test.errors)
+				break
+			}
+		}
+	}
+
+	// enregistrement binaire tronqué
+	c := NewClusterer(1, 1, 2, 3)
+	truncated := binary.Bytes()[:binary.Len()-4]
+	if err := c.ConsumeReader(context.Background(), bytes.NewReader(truncated), BinaryFormat); err == nil {
+		t.Error("truncated binary stream should fail")
+	}
+}
+
+func TestStreamDecode(t *testing.T) {
+	s := NewStream()
+	errors := make(chan error, 10)
+	s.Errors = errors
+	out := make(chan []float64, 10)
+	if err := s.Decode(context.Background(), strings.NewReader("[1,2]\n[3]\n[4,5]\n"), JSONLinesFormat, out); err != nil {
+		t.Fatal(err)
+	}
+	var rows [][]float64
+	for x := range out { // Decode ferme le canal
+		rows = append(rows, x)
+	}
+	if fmt.Sprint(rows) != "[[1 2] [4 5]]" {
+		t.Errorf("decoded rows %v, expected [[1 2] [4 5]]", rows)
+	}
+	close(errors)
+	for err := range errors {
+		if err.(*RowError).Row != 2 {
+			t.Errorf("unexpected error %v", err)
+		}
+	}
+
+	// sans lecteur, l'annulation interrompt le décodage
+	ctx, cancel := context.WithCancel(context.Background())
+	blocked := make(chan []float64)
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Decode(ctx, strings.NewReader("1,2\n3,4\n"), CSVFormat, blocked)
+	}()
+	cancel()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("cancelled decoding returned %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("cancelled decoding did not return")
+	}
+	if _, open := <-blocked; open {
+		t.Error("output channel not closed after cancellation")
+	}
+}
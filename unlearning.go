@@ -0,0 +1,146 @@
+package microClustering
+
+import (
+	"fmt"
+)
+
+/*
+  Désapprentissage : suppression d'une mesure déjà apprise
+
+  - le µC ayant absorbé la mesure est celui dont le reservoir contient la mesure, sinon le µC le plus proche
+    dont la sphère contient la mesure
+  - la contribution de la mesure est retirée du poids, du centre, des zones et des cluster features du µC,
+    ainsi que du reservoir ; le µC est supprimé lorsqu'il est vide
+  - la suppression est exacte lorsque les cluster features sont maintenues (TrackFeatures) : le centre est recalculé
+    à partir de LS. Sinon le centre est recalculé en inversant la moyenne incrémentale, ce qui est approché si le µC
+    a été modifié par l'oubli ou une fusion depuis l'ajout de la mesure.
+  - la zone de la mesure est toujours estimée à partir de sa distance actuelle au centre du µC
+*/
+
+// Remove retire la mesure 'x' du µC qui l'a absorbée
+func (c *Clusterer) Remove(x []float64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id := c.absorber(x)
+	if id < 0 {
+		return fmt.Errorf("no micro-cluster contains the point")
+	}
+	mc := c.mc[id]
+	if mc.Weight <= 1 {
+		c.removeMC(id)
+		c.updateStats()
+		return nil
+	}
+
+	radius := c.radiusOf(mc)
+	dist := c.distance(x, mc.Center)
+	w := float64(mc.Weight)
+	if mc.LS != nil {
+		for i := range x {
+			mc.LS[i] -= x[i]
+			mc.SS[i] -= x[i] * x[i]
+			mc.Center[i] = mc.LS[i] / (w - 1)
+		}
+	} else {
+		for i := range x {
+			mc.Center[i] = (w*mc.Center[i] - x[i]) / (w - 1)
+		}
+	}
+	mc.Weight--
+	mc.removeFromZone(dist, radius)
+	mc.unsample(x)
+	c.updateRadius(mc)
+	c.updateStats()
+	return nil
+}
+
+// absorber renvoie l'indice du µC ayant absorbé la mesure 'x', -1 si aucun µC ne la contient
+func (c *Clusterer) absorber(x []float64) int {
+	for i, mc := range c.mc {
+		if mc.exemplar(x) >= 0 {
+			return i
+		}
+	}
+	nearest := -1
+	min := 0.0
+	for i, mc := range c.mc {
+		d := c.distance(x, mc.Center)
+		if d <= c.radiusOf(mc) && (nearest < 0 || d < min) {
+			nearest, min = i, d
+		}
+	}
+	return nearest
+}
+
+// removeFromZone décrémente la zone correspondant à la distance 'dist' au centre, ou la zone non vide la plus proche
+func (mc *microcluster) removeFromZone(dist float64, radius float64) {
+	n := len(mc.Zones)
+	if n == 0 {
+		return
+	}
+	zone := n - 1
+	for z := 0; z < n; z++ {
+		if dist <= float64(z+1)*radius/float64(n) {
+			zone = z
+			break
+		}
+	}
+	for delta := 0; delta < n; delta++ {
+		for _, z := range []int{zone - delta, zone + delta} {
+			if z >= 0 && z < n && mc.Zones[z] > 0 {
+				mc.Zones[z]--
+				return
+			}
+		}
+	}
+}
+
+// exemplar renvoie l'indice de la mesure 'x' dans le reservoir du µC, -1 si elle n'y est pas
+func (mc *microcluster) exemplar(x []float64) int {
+	for i, e := range mc.Exemplars {
+		if sameVector(e.Point, x) {
+			return i
+		}
+	}
+	return -1
+}
+
+// sameVector renvoie true si les deux vecteurs sont identiques
+func sameVector(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// unsample retire la mesure 'x' du reservoir du µC
+func (mc *microcluster) unsample(x []float64) {
+	if mc.Seen > 0 {
+		mc.Seen--
+	}
+	if i := mc.exemplar(x); i >= 0 {
+		mc.Exemplars = append(mc.Exemplars[:i], mc.Exemplars[i+1:]...)
+	}
+}
+
+// Unfit retire la mesure 'x' de la classe 'y' : la mesure est supprimée du buffer de démarrage si elle y est encore,
+// sinon du µC de la classe qui l'a absorbée (voir Clusterer.Remove)
+func (c *Classifier) Unfit(x []float64, y int) error {
+	for i := range c.warmUpData {
+		if c.warmUpLabels[i] == y && sameVector(c.warmUpData[i], x) {
+			c.warmUpData = append(c.warmUpData[:i], c.warmUpData[i+1:]...)
+			c.warmUpLabels = append(c.warmUpLabels[:i], c.warmUpLabels[i+1:]...)
+			return nil
+		}
+	}
+	cl, exists := c.classes[y]
+	if !exists {
+		return fmt.Errorf("unknown class %d", y)
+	}
+	return cl.Remove(x)
+}
@@ -0,0 +1,80 @@
+package microClustering
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRemove(t *testing.T) {
+	points := [][]float64{{0, 0}, {0.4, 0.2}, {0.2, 0.6}, {5, 5}}
+
+	for _, track := range []bool{true, false} {
+		c := NewClusterer(1, 1, 2, 3)
+		c.TrackFeatures = track
+		c.ReservoirSize = 10
+		c.Add(points)
+		if c.CountMC() != 2 {
+			t.Fatalf("expected 2 µC, got %d", c.CountMC())
+		}
+
+		if err := c.Remove(points[1]); err != nil {
+			t.Fatal(err)
+		}
+		mc := c.mc[0]
+		if mc.Weight != 2 {
+			t.Errorf("track=%v: weight %d after removal, expected 2", track, mc.Weight)
+		}
+		expected := []float64{0.1, 0.3} // moyenne des mesures restantes
+		for i := range expected {
+			if math.Abs(mc.Center[i]-expected[i]) > 1e-9 {
+				t.Errorf("track=%v: center %v, expected %v", track, mc.Center, expected)
+				break
+			}
+		}
+		if mc.exemplar(points[1]) >= 0 {
+			t.Errorf("track=%v: removed point still in the reservoir", track)
+		}
+		if zones := mc.Zones[0] + mc.Zones[1]; zones != 2 {
+			t.Errorf("track=%v: zones %v, expected 2 points", track, mc.Zones)
+		}
+		if track && (math.Abs(mc.LS[0]-0.2) > 1e-9 || math.Abs(mc.SS[1]-0.36) > 1e-9) {
+			t.Errorf("cluster features LS=%v SS=%v", mc.LS, mc.SS)
+		}
+
+		// un µC vide est supprimé
+		if err := c.Remove(points[3]); err != nil {
+			t.Fatal(err)
+		}
+		if c.CountMC() != 1 {
+			t.Errorf("track=%v: empty µC should be dropped, %d µC left", track, c.CountMC())
+		}
+		if err := c.Remove([]float64{20, 20}); err == nil {
+			t.Errorf("track=%v: removing an unknown point should fail", track)
+		}
+	}
+}
+
+func TestUnfit(t *testing.T) {
+	c := NewClassifier(0, 0, 1, 1, 3)
+	c.WarmUp = 10
+	c.PartialFit([]float64{0, 0}, 0)
+	c.PartialFit([]float64{1, 1}, 0)
+	if err := c.Unfit([]float64{1, 1}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.warmUpData) != 1 {
+		t.Errorf("point should be removed from the warm-up buffer, %d left", len(c.warmUpData))
+	}
+
+	c = NewClassifier(0, 1, 1, 1, 3)
+	c.FitXY([][]float64{{0, 0}, {0.2, 0}, {5, 5}}, []int{0, 0, 1})
+	if err := c.Unfit([]float64{0.2, 0}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if w := c.classes[0].totalWeight(); w != 1 {
+		t.Errorf("class weight %d after unfit, expected 1", w)
+	}
+	if err := c.Unfit([]float64{0, 0}, 2); err == nil {
+		t.Error("unfit of an unknown class should fail")
+	}
+}